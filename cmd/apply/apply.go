@@ -1,23 +1,82 @@
 package apply
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/nicktrav/matryoshka/pkg/actions"
 	"github.com/nicktrav/matryoshka/pkg/graph"
 	"github.com/nicktrav/matryoshka/pkg/lang"
+	"github.com/nicktrav/matryoshka/pkg/state"
 )
 
 var (
-	dir     string
-	rootDep string
-	noColor bool
-	debug   bool
-	dryRun  bool
+	dir      string
+	rootDeps []string
+	tags     []string
+	excludes []string
+	noColor  bool
+	debug    bool
+	dryRun   bool
+	jobs     int
+	timeout  time.Duration
+
+	keepGoing bool
+	failFast  bool
+
+	planFile string
+
+	incremental bool
+	ttl         time.Duration
+	forced      []string
+
+	reportFormat string
+	reportFile   string
+
+	logFile string
+)
+
+const (
+	defaultRoot = "all"
+
+	// defaultTTL is the length of time for which a dep verified as met is
+	// assumed to remain met, in an incremental apply
+	defaultTTL = 24 * time.Hour
+
+	reportJSON = "json"
+
+	// sudoRefresh is the interval at which sudo credentials are refreshed
+	sudoRefresh = time.Minute
 )
 
-const defaultRoot = "all"
+// errNotSatisfied is returned once a run completes with deps that are not
+// satisfied.
+var errNotSatisfied = errors.New("not all deps were satisfied")
+
+// runError is an error that occurred once the deps were being applied, rather
+// than one in how the command was invoked, for which the usage is not printed.
+type runError struct {
+	err error
+}
+
+// Error returns the message of the underlying error.
+func (e *runError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *runError) Unwrap() error {
+	return e.err
+}
 
 // NewCommand returns a new command for applying dependencies.
 func NewCommand() *cobra.Command {
@@ -25,15 +84,59 @@ func NewCommand() *cobra.Command {
 		Use:   "apply",
 		Short: "Apply dependencies",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return run()
+			// deps selected by tag replace the default root, rather than
+			// being applied in addition to it
+			if len(tags) > 0 && !cmd.Flags().Changed("dep") {
+				rootDeps = nil
+			}
+
+			// a plan records the deps it applies to, and what to do for
+			// each of them
+			if planFile != "" {
+				for _, flag := range []string{"dep", "tag", "exclude", "dry-run", "incremental"} {
+					if cmd.Flags().Changed(flag) {
+						return fmt.Errorf("--plan cannot be combined with --%s", flag)
+					}
+				}
+			}
+
+			if !incremental {
+				for _, flag := range []string{"ttl", "force"} {
+					if cmd.Flags().Changed(flag) {
+						return fmt.Errorf("--%s requires --incremental", flag)
+					}
+				}
+			}
+
+			err := run()
+			var re *runError
+			if errors.Is(err, errNotSatisfied) || errors.As(err, &re) {
+				// the failures have already been described in full, or
+				// have nothing to do with the usage
+				cmd.SilenceUsage = true
+			}
+			return err
 		},
 	}
 
 	cmd.Flags().StringVar(&dir, "dir", "", "Directory of deps")
-	cmd.Flags().StringVar(&rootDep, "dep", defaultRoot, "Root of the dependency graph")
+	cmd.Flags().StringSliceVar(&rootDeps, "dep", []string{defaultRoot}, "Root of the dependency graph; may be repeated")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Apply the deps with the tag, along with their requirements; may be repeated")
+	cmd.Flags().StringSliceVar(&excludes, "exclude", nil, "Skip the dep, along with any requirements only it requires; may be repeated")
 	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable color printing")
 	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug output")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Do not attempt to satisfy dependencies")
+	cmd.Flags().IntVar(&jobs, "jobs", 1, "Maximum number of dependencies to apply concurrently")
+	cmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Attempt every dep not blocked by a failure (the default)")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop attempting deps after the first failure")
+	cmd.Flags().StringVar(&planFile, "plan", "", "Apply exactly the plan in the file, as written by the plan command")
+	cmd.Flags().BoolVar(&incremental, "incremental", false, "Skip deps that are unchanged, and were verified as met within the TTL")
+	cmd.Flags().DurationVar(&ttl, "ttl", defaultTTL, "Length of time for which a dep verified as met is not verified again, with --incremental")
+	cmd.Flags().StringSliceVar(&forced, "force", nil, "Verify the dep even if it would be skipped, with --incremental; may be repeated")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Maximum time for the whole run (e.g. 30m); zero means no limit")
+	cmd.Flags().StringVar(&reportFormat, "report", "", "Format of the run report to write (json)")
+	cmd.Flags().StringVar(&reportFile, "report-file", "", "File to write the run report to (defaults to stdout, with all other output written to stderr)")
+	cmd.Flags().StringVar(&logFile, "log-file", "", "File to write a log of each action, its output and each state change to")
 
 	return cmd
 }
@@ -44,36 +147,355 @@ func run() error {
 		return errors.New("dir is a required argument")
 	}
 
+	if jobs < 1 {
+		return errors.New("jobs must be at least 1")
+	}
+
+	if keepGoing && failFast {
+		return errors.New("keep-going and fail-fast are mutually exclusive")
+	}
+
+	if reportFormat != "" && reportFormat != reportJSON {
+		return fmt.Errorf("unsupported report format: %s", reportFormat)
+	}
+
+	if reportFile != "" && reportFormat == "" {
+		return errors.New("report-file requires a report format")
+	}
+
 	parser := lang.NewParser(dir)
 	err := parser.Run()
 	if err != nil {
 		return err
 	}
 
+	selection := graph.Selection{
+		Roots:   rootDeps,
+		Tags:    tags,
+		Exclude: excludes,
+	}
+
+	var plan *graph.Plan
+	if planFile != "" {
+		if plan, err = readPlan(); err != nil {
+			return err
+		}
+		selection = plan.Selection
+	}
+
 	depGraph := graph.NewDependencyGraph()
-	depGraph.Construct(parser.Deps())
+	err = depGraph.Construct(parser.Deps())
+	if err != nil {
+		return err
+	}
 
-	var printOptions []graph.PrintOption
+	roots, err := depGraph.Select(selection)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return errors.New("no deps selected")
+	}
+
+	if plan != nil {
+		if err := checkPlan(plan, parser, depGraph, roots); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := runContext()
+	defer cancel()
+
+	// acquire any sudo credentials up front, rather than prompting mid-run
+	if err := acquireSudo(ctx, depGraph, roots); err != nil {
+		return err
+	}
+
+	// the report is the only output written to stdout when no report file
+	// is given, such that it can be parsed
+	out := os.Stdout
+	if reportFormat != "" && reportFile == "" {
+		out = os.Stderr
+	}
+
+	printOptions := []graph.PrintOption{graph.WithWriter(out)}
 	if !noColor {
 		printOptions = append(printOptions, graph.WithColor)
 	}
-	printer := graph.NewDepPrinter(printOptions...)
+	if jobs > 1 {
+		printOptions = append(printOptions, graph.Flat)
+	}
 
-	var executorOptions []graph.ExecutorOption
+	// the printer, progress, reporter and log are each subscribers to the
+	// events of the run
+	events := graph.NewEvents()
+
+	executorOptions := []graph.ExecutorOption{graph.WithContext(ctx), graph.WithEvents(events)}
 	if debug {
 		executorOptions = append(executorOptions, graph.Debug)
 	}
 	if dryRun {
 		executorOptions = append(executorOptions, graph.DryRun)
 	}
-	executor := graph.NewExecutor(executorOptions...)
+	if failFast {
+		executorOptions = append(executorOptions, graph.FailFast)
+	}
+	if plan != nil {
+		executorOptions = append(executorOptions, graph.WithPlan(plan))
+	}
+	if incremental {
+		verified, err := verifiedDeps(depGraph)
+		if err != nil {
+			return err
+		}
+		executorOptions = append(executorOptions, graph.WithVerified(verified))
+	}
+
+	// draw the progress of the run when writing to a terminal, unless debug
+	// output, which is written as it is produced, would be interleaved with
+	// it. Otherwise, print each dep as it completes.
+	var progress *graph.Progress
+	if width, ok := graph.TerminalWidth(out); ok && !debug {
+		progress = graph.NewProgress(out, width, printOptions...)
+		events.Subscribe(progress)
+	} else {
+		events.Subscribe(graph.NewDepPrinter(printOptions...))
+	}
 
-	v := graph.NewCompositeVisitor(printer, executor)
+	// the reporter records each run in the state store, as well as writing
+	// the report, if any
+	reporter := graph.NewReporter()
+	events.Subscribe(reporter)
+
+	if logFile != "" {
+		f, err := os.Create(logFile)
+		if err != nil {
+			return fmt.Errorf("could not create log file: %s", err)
+		}
+		defer f.Close()
+		events.Subscribe(graph.NewEventLog(f))
+	}
+
+	v := events.Visitor(graph.NewExecutor(executorOptions...))
 	walker := graph.NewWalker(v)
-	err = walker.Walk(depGraph, rootDep)
+	if jobs > 1 {
+		walker = graph.NewConcurrentWalker(jobs, v)
+	}
+
+	if progress != nil {
+		progress.Start()
+	}
+	err = walker.Walk(depGraph, roots...)
+	if progress != nil {
+		progress.Stop()
+	}
+
+	// the report is written regardless of the outcome of the walk, as it is
+	// most useful when something has gone wrong
+	if reportFormat != "" {
+		if reportErr := writeReport(reporter); reportErr != nil && err == nil {
+			err = reportErr
+		}
+	}
+
+	// as is the run, unless no dep was visited. The outcome of the run does
+	// not depend on whether it could be recorded
+	if len(reporter.Report().Deps) > 0 {
+		if recordErr := recordRun(reporter, roots); recordErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", recordErr)
+		}
+	}
+
+	if err != nil {
+		return &runError{err}
+	}
+
+	// the deps that were not satisfied are summarized even if the run was
+	// stopped, as the deps that were stopped are among the root causes
+	summaryErr := summarize(out, depGraph, roots, progress == nil)
+	if err := ctx.Err(); err != nil {
+		return &runError{fmt.Errorf("run stopped: %s", err)}
+	}
+
+	return summaryErr
+}
+
+// summarize prints the root causes of any deps that are not satisfied to the
+// given writer, preceded by the number of deps in each state if totals is
+// true. An error is returned if any dep is not satisfied.
+func summarize(w io.Writer, depGraph *graph.DependencyGraph, roots []string, totals bool) error {
+	var deps []*graph.Dependency
+	for _, root := range roots {
+		deps = append(deps, depGraph.Get(root))
+	}
+
+	summary, err := graph.Summarize(deps...)
+	if err != nil {
+		return err
+	}
+
+	if totals {
+		fmt.Fprintln(w, summary.Totals())
+	}
+	if err := summary.WriteRootCauses(w); err != nil {
+		return err
+	}
+
+	if n := len(summary.RootCauses); n > 0 {
+		return fmt.Errorf("%w: %d root cause(s)", errNotSatisfied, n)
+	}
+	return nil
+}
+
+// readPlan reads the plan from the plan file.
+func readPlan() (*graph.Plan, error) {
+	f, err := os.Open(planFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not open plan file: %s", err)
+	}
+	defer f.Close()
+
+	return graph.ReadPlan(f)
+}
+
+// checkPlan returns an error if the dep sources read by the given parser, or
+// the templates rendered by the deps reachable from the given roots, have
+// changed since the given plan was made.
+func checkPlan(plan *graph.Plan, parser lang.Parser, depGraph *graph.DependencyGraph, roots []string) error {
+	var deps []*graph.Dependency
+	for _, root := range roots {
+		deps = append(deps, depGraph.Get(root))
+	}
+
+	digest, err := lang.Digest(dir, parser.Modules(), graph.Sources(deps...))
 	if err != nil {
 		return err
 	}
+	if digest != plan.Digest {
+		return errors.New("dep sources or templates have changed since the plan was made; run plan again")
+	}
 
 	return nil
 }
+
+// runContext returns a context for the run, along with a function to release
+// its resources. The context is done once the timeout (if any) has elapsed, or
+// once the process receives an interrupt. A second interrupt is handled as
+// normal, terminating the process immediately.
+func runContext() (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(signals)
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// acquireSudo acquires sudo credentials if any of the deps reachable from the
+// given roots require them, keeping the credentials alive until the given
+// context is done. The user is only prompted for a password when running
+// interactively.
+func acquireSudo(ctx context.Context, depGraph *graph.DependencyGraph, roots []string) error {
+	var deps []*graph.Dependency
+	for _, root := range roots {
+		deps = append(deps, depGraph.Get(root))
+	}
+
+	names := graph.PrivilegedDeps(!dryRun, deps...)
+	if len(names) == 0 {
+		return nil
+	}
+
+	if err := actions.AcquireSudoFor(ctx, names); err != nil {
+		return err
+	}
+
+	go actions.KeepSudoAlive(ctx, sudoRefresh)
+
+	return nil
+}
+
+// verifiedDeps returns a function that returns true for each dep that need
+// not be verified again: those that were last verified as met within the TTL,
+// and whose definition, along with those of the deps they require, are
+// unchanged since. Forced deps are always verified.
+func verifiedDeps(depGraph *graph.DependencyGraph) (func(dep *graph.Dependency) bool, error) {
+	force := make(map[string]bool)
+	for _, name := range forced {
+		if depGraph.Get(name) == nil {
+			return nil, fmt.Errorf("dep %s not found", name)
+		}
+		force[name] = true
+	}
+
+	stateDir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	verifications, err := state.NewStore(stateDir).Verifications()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return func(dep *graph.Dependency) bool {
+		last, ok := verifications[dep.Name]
+		return ok && !force[dep.Name] && last.Fresh(graph.Closure(dep), ttl, now)
+	}, nil
+}
+
+// recordRun records the run reported by the given Reporter in the state
+// store.
+func recordRun(reporter *graph.Reporter, roots []string) error {
+	stateDir, err := state.Dir()
+	if err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	run := state.NewRun(absDir, roots, dryRun, reporter.Report())
+	if err := state.NewStore(stateDir).Record(run); err != nil {
+		return fmt.Errorf("could not record run: %s", err)
+	}
+	return nil
+}
+
+// writeReport writes the report from the given Reporter to the report file,
+// or to stdout if no file was given.
+func writeReport(reporter *graph.Reporter) error {
+	if reportFile == "" {
+		return reporter.WriteJSON(os.Stdout)
+	}
+
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return fmt.Errorf("could not create report file: %s", err)
+	}
+
+	if err := reporter.WriteJSON(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not write report: %s", err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,90 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nicktrav/matryoshka/pkg/graph"
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+var (
+	dir     string
+	rootDep string
+)
+
+const defaultRoot = "all"
+
+// NewCommand returns a new command for checking a directory of deps.
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check deps for mistakes without applying them",
+		Long: `Check parses a directory of deps and constructs the dependency graph,
+without running any commands. Errors, such as undefined or cyclic
+requirements, are reported along with likely mistakes:
+
+  - deps not reachable from the root dep
+  - deps with meet commands, but no met commands
+  - deps requiring disabled deps
+  - symbols loaded via load() that are never used
+
+The command exits non-zero if any are found.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run()
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory of deps")
+	cmd.Flags().StringVar(&rootDep, "dep", defaultRoot, "Root of the dependency graph")
+
+	return cmd
+}
+
+// run checks the deps in a given directory, printing any problems found.
+func run() error {
+	if dir == "" {
+		return errors.New("dir is a required argument")
+	}
+
+	parser := lang.NewParser(dir)
+	if err := parser.Run(); err != nil {
+		return err
+	}
+
+	depGraph := graph.NewDependencyGraph()
+	if err := depGraph.Construct(parser.Deps()); err != nil {
+		return err
+	}
+
+	problems := lang.LintDeps(parser.Deps(), rootDep)
+	for _, module := range parser.Modules() {
+		src, err := ioutil.ReadFile(module)
+		if err != nil {
+			return err
+		}
+		moduleProblems, err := lang.LintModule(module, src)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, moduleProblems...)
+	}
+	lang.SortProblems(problems)
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New("found 1 problem")
+	default:
+		return fmt.Errorf("found %d problems", len(problems))
+	}
+}
@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+	depgraph "github.com/nicktrav/matryoshka/pkg/graph"
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+var (
+	dir       string
+	rootDep   string
+	format    string
+	withState bool
+)
+
+const defaultRoot = "all"
+
+// NewCommand returns a new command for exporting the dep graph.
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Export the dep graph for rendering",
+		Long: `Graph writes the deps reachable from the root dep to stdout, annotated
+with their description and the module in which they are declared, in a
+format that can be rendered, e.g. with Graphviz or Mermaid.
+
+With --state, the met actions of each dep are run, as by the status
+command, and each dep is annotated with whether it is satisfied.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run()
+		},
+	}
+
+	var formats []string
+	for _, f := range depgraph.Formats {
+		formats = append(formats, string(f))
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory of deps")
+	cmd.Flags().StringVar(&rootDep, "root", defaultRoot, "Root of the dependency graph")
+	cmd.Flags().StringVar(&format, "format", string(depgraph.DOT), "Output format ("+strings.Join(formats, "|")+")")
+	cmd.Flags().BoolVar(&withState, "state", false, "Run the met actions of each dep and annotate it with its state")
+
+	return cmd
+}
+
+// run exports the dep graph from a given directory.
+func run() error {
+	if dir == "" {
+		return errors.New("dir is a required argument")
+	}
+
+	if !supported(depgraph.Format(format)) {
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+
+	parser := lang.NewParser(dir)
+	if err := parser.Run(); err != nil {
+		return err
+	}
+
+	depGraph := depgraph.NewDependencyGraph()
+	if err := depGraph.Construct(parser.Deps()); err != nil {
+		return err
+	}
+
+	root := depGraph.Get(rootDep)
+	if root == nil {
+		return fmt.Errorf("root dep %s not found", rootDep)
+	}
+
+	var options []depgraph.ExportOption
+	if withState {
+		if err := evaluate(depGraph, root); err != nil {
+			return err
+		}
+		options = append(options, depgraph.WithState)
+	}
+
+	return depgraph.Export(os.Stdout, root, depgraph.Format(format), options...)
+}
+
+// evaluate determines the state of each of the deps reachable from the given
+// root by running their met actions.
+func evaluate(g *depgraph.DependencyGraph, root *depgraph.Dependency) error {
+	ctx := context.Background()
+	if err := actions.AcquireSudoFor(ctx, depgraph.PrivilegedDeps(false, root)); err != nil {
+		return err
+	}
+
+	checker := depgraph.NewStatusChecker(depgraph.WithContext(ctx))
+	return depgraph.NewWalker(checker).Walk(g, root.Name)
+}
+
+// supported returns true if the given format is a supported export format.
+func supported(f depgraph.Format) bool {
+	for _, supported := range depgraph.Formats {
+		if f == supported {
+			return true
+		}
+	}
+	return false
+}
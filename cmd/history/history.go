@@ -0,0 +1,204 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nicktrav/matryoshka/pkg/graph"
+	"github.com/nicktrav/matryoshka/pkg/state"
+)
+
+var limit int
+
+const (
+	defaultLimit = 20
+
+	// timeFormat is the format of the times printed
+	timeFormat = "2006-01-02 15:04:05"
+
+	// definitionLength is the number of characters of a definition digest
+	// printed
+	definitionLength = 12
+)
+
+// NewCommand returns a new command for querying past applies.
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [dep]",
+		Short: "Show past applies",
+		Long: `History lists past applies, most recent first, as recorded in the state
+store ($XDG_STATE_HOME/matryoshka, or ~/.local/state/matryoshka).
+
+Given the name of a dependency, history lists the outcome of the dependency
+in each past apply that included it, along with a digest of its definition
+at the time.`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				return runDep(args[0])
+			}
+			return run()
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", defaultLimit, "Maximum number of applies to show; zero shows all")
+
+	return cmd
+}
+
+// run lists past applies.
+func run() error {
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	runs, err := store.Runs()
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("no applies recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tSTART\tDURATION\tDEPS\tROOTS\tDIR")
+	for _, r := range recent(runs) {
+		counts := make(map[graph.State]int)
+		for _, dep := range r.Deps {
+			counts[dep.State]++
+		}
+
+		deps := graph.FormatTotals(counts)
+		if r.DryRun {
+			deps += " (dry run)"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.ID, r.Start.Local().Format(timeFormat), duration(r.Start, r.End), deps, strings.Join(r.Roots, ","), r.Dir)
+	}
+	return w.Flush()
+}
+
+// runDep lists the outcome of the dep with the given name in past applies.
+func runDep(name string) error {
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	runs, err := store.History(name)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no applies recorded for dep %s", name)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "START\tSTATE\tDURATION\tDEFINITION\tRUN\tDIR")
+	for _, r := range recent(runs) {
+		dep := r.Dep(name)
+
+		s := dep.State.String()
+		if dep.Cached {
+			s += " (cached)"
+		}
+		if r.DryRun {
+			s += " (dry run)"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			dep.Start.Local().Format(timeFormat), s, duration(dep.Start, dep.End), short(dep.Definition), r.ID, r.Dir)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return printLastConverged(os.Stdout, name, runs)
+}
+
+// printLastConverged prints the last time the dep with the given name was
+// satisfied by an apply, other than a dry run, along with its definition, and
+// the last time its meet actions were run to satisfy it, if ever.
+func printLastConverged(w io.Writer, name string, runs []*state.Run) error {
+	converged := lastRun(name, runs, (*state.DepRun).Converged)
+	if converged == nil {
+		_, err := fmt.Fprintf(w, "\n%s has never converged\n", name)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%s last converged at %s, with definition %s\n",
+		name, converged.End.Local().Format(timeFormat), converged.Definition)
+	if err != nil {
+		return err
+	}
+
+	if enforced := lastRun(name, runs, (*state.DepRun).Enforced); enforced != nil {
+		_, err = fmt.Fprintf(w, "%s last met by its meet actions at %s, with definition %s\n",
+			name, enforced.End.Local().Format(timeFormat), enforced.Definition)
+	}
+	return err
+}
+
+// lastRun returns the outcome of the dep with the given name in the most
+// recent of the given runs, other than a dry run, for which the given
+// predicate is true, or nil if there is no such run.
+func lastRun(name string, runs []*state.Run, predicate func(*state.DepRun) bool) *state.DepRun {
+	for i := len(runs) - 1; i >= 0; i-- {
+		dep := runs[i].Dep(name)
+		if !runs[i].DryRun && dep != nil && predicate(dep) {
+			return dep
+		}
+	}
+	return nil
+}
+
+// openStore returns the state store.
+func openStore() (*state.Store, error) {
+	if limit < 0 {
+		return nil, errors.New("limit must not be negative")
+	}
+
+	dir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return state.NewStore(dir), nil
+}
+
+// recent returns the given runs, most recent first, up to the limit.
+func recent(runs []*state.Run) []*state.Run {
+	var reversed []*state.Run
+	for i := len(runs) - 1; i >= 0; i-- {
+		if limit > 0 && len(reversed) == limit {
+			break
+		}
+		reversed = append(reversed, runs[i])
+	}
+	return reversed
+}
+
+// duration returns the length of time between the given times, rounded for
+// display.
+func duration(start, end time.Time) string {
+	return end.Sub(start).Round(time.Millisecond).String()
+}
+
+// short returns the given digest, truncated for display.
+func short(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > definitionLength {
+		return digest[:definitionLength]
+	}
+	return digest
+}
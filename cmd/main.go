@@ -6,7 +6,12 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/nicktrav/matryoshka/cmd/apply"
+	"github.com/nicktrav/matryoshka/cmd/check"
+	"github.com/nicktrav/matryoshka/cmd/graph"
+	"github.com/nicktrav/matryoshka/cmd/history"
+	"github.com/nicktrav/matryoshka/cmd/plan"
 	"github.com/nicktrav/matryoshka/cmd/print"
+	"github.com/nicktrav/matryoshka/cmd/status"
 	"github.com/nicktrav/matryoshka/cmd/version"
 )
 
@@ -29,6 +34,11 @@ func newCommand(args []string) *cobra.Command {
 	rootCmd.SetArgs(args)
 	rootCmd.AddCommand(print.NewCommand())
 	rootCmd.AddCommand(apply.NewCommand())
+	rootCmd.AddCommand(check.NewCommand())
+	rootCmd.AddCommand(graph.NewCommand())
+	rootCmd.AddCommand(history.NewCommand())
+	rootCmd.AddCommand(plan.NewCommand())
+	rootCmd.AddCommand(status.NewCommand())
 	rootCmd.AddCommand(version.NewCommand())
 
 	return rootCmd
@@ -0,0 +1,140 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+	"github.com/nicktrav/matryoshka/pkg/graph"
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+var (
+	dir      string
+	rootDeps []string
+	tags     []string
+	excludes []string
+	out      string
+	debug    bool
+)
+
+const (
+	defaultRoot = "all"
+	defaultOut  = "plan.json"
+)
+
+// NewCommand returns a new command for planning the application of
+// dependencies.
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Write a plan of what applying dependencies would do",
+		Long: `Plan runs the met actions of each selected dependency, and writes a plan
+recording which dependencies are already met, and which meet actions would
+run, in the order in which they would run. The meet actions of a dependency
+are never run, but are previewed where possible.
+
+The plan can be reviewed, and then applied exactly with "apply --plan".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// deps selected by tag replace the default root, rather than
+			// being planned in addition to it
+			if len(tags) > 0 && !cmd.Flags().Changed("dep") {
+				rootDeps = nil
+			}
+			return run()
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory of deps")
+	cmd.Flags().StringSliceVar(&rootDeps, "dep", []string{defaultRoot}, "Root of the dependency graph; may be repeated")
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Plan the deps with the tag, along with their requirements; may be repeated")
+	cmd.Flags().StringSliceVar(&excludes, "exclude", nil, "Skip the dep, along with any requirements only it requires; may be repeated")
+	cmd.Flags().StringVar(&out, "out", defaultOut, "File to write the plan to")
+	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug output")
+
+	return cmd
+}
+
+// run writes a plan for the dependencies in a given directory.
+func run() error {
+	if dir == "" {
+		return errors.New("dir is a required argument")
+	}
+
+	parser := lang.NewParser(dir)
+	if err := parser.Run(); err != nil {
+		return err
+	}
+
+	depGraph := graph.NewDependencyGraph()
+	if err := depGraph.Construct(parser.Deps()); err != nil {
+		return err
+	}
+
+	selection := graph.Selection{
+		Roots:   rootDeps,
+		Tags:    tags,
+		Exclude: excludes,
+	}
+	roots, err := depGraph.Select(selection)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return errors.New("no deps selected")
+	}
+
+	var deps []*graph.Dependency
+	for _, root := range roots {
+		deps = append(deps, depGraph.Get(root))
+	}
+
+	digest, err := lang.Digest(dir, parser.Modules(), graph.Sources(deps...))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := actions.AcquireSudoFor(ctx, graph.PrivilegedDeps(false, deps...)); err != nil {
+		return err
+	}
+
+	plannerOptions := []graph.ExecutorOption{graph.WithContext(ctx)}
+	if debug {
+		plannerOptions = append(plannerOptions, graph.Debug)
+	}
+
+	if err := graph.NewWalker(graph.NewPlanner(plannerOptions...)).Walk(depGraph, roots...); err != nil {
+		return err
+	}
+
+	plan, err := graph.NewPlan(selection, digest, deps...)
+	if err != nil {
+		return err
+	}
+
+	if err := writePlan(plan); err != nil {
+		return err
+	}
+
+	return plan.WriteText(os.Stdout)
+}
+
+// writePlan writes the given plan to the plan file.
+func writePlan(plan *graph.Plan) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("could not create plan file: %s", err)
+	}
+
+	if err := plan.WriteJSON(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not write plan: %s", err)
+	}
+
+	return f.Close()
+}
@@ -45,7 +45,10 @@ func run() error {
 	}
 
 	depGraph := graph.NewDependencyGraph()
-	depGraph.Construct(parser.Deps())
+	err = depGraph.Construct(parser.Deps())
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Println("Found the following dependencies:")
 	fmt.Println()
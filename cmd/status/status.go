@@ -0,0 +1,79 @@
+package status
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+	"github.com/nicktrav/matryoshka/pkg/graph"
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+var (
+	dir     string
+	rootDep string
+	noColor bool
+	debug   bool
+)
+
+const defaultRoot = "all"
+
+// NewCommand returns a new command for showing the status of dependencies.
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show which dependencies are met, without meeting them",
+		Long: `Status runs the met actions of each dependency reachable from the root
+dependency, and prints whether each is satisfied. The meet actions of a
+dependency are never run, or previewed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run()
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory of deps")
+	cmd.Flags().StringVar(&rootDep, "dep", defaultRoot, "Root of the dependency graph")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable color printing")
+	cmd.Flags().BoolVar(&debug, "debug", false, "Enable debug output")
+
+	return cmd
+}
+
+// run prints the status of the dependencies in a given directory.
+func run() error {
+	if dir == "" {
+		return errors.New("dir is a required argument")
+	}
+
+	parser := lang.NewParser(dir)
+	if err := parser.Run(); err != nil {
+		return err
+	}
+
+	depGraph := graph.NewDependencyGraph()
+	if err := depGraph.Construct(parser.Deps()); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := actions.AcquireSudoFor(ctx, graph.PrivilegedDeps(false, depGraph.Get(rootDep))); err != nil {
+		return err
+	}
+
+	var printOptions []graph.PrintOption
+	if !noColor {
+		printOptions = append(printOptions, graph.WithColor)
+	}
+
+	events := graph.NewEvents(graph.NewDepPrinter(printOptions...))
+
+	checkerOptions := []graph.ExecutorOption{graph.WithContext(ctx), graph.WithEvents(events)}
+	if debug {
+		checkerOptions = append(checkerOptions, graph.Debug)
+	}
+
+	v := events.Visitor(graph.NewStatusChecker(checkerOptions...))
+	return graph.NewWalker(v).Walk(depGraph, rootDep)
+}
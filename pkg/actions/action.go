@@ -1,11 +1,19 @@
 package actions
 
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
 // Action represents some action to take.
 type Action interface {
 
 	// Run attempts to perform the action action, returning an error if the
-	// action could not be completed.
-	Run() error
+	// action could not be completed. The action should stop as soon as
+	// possible if the given context is done, returning an error that wraps
+	// the error from the context.
+	Run(ctx context.Context) error
 }
 
 // Debugger takes an appropriate debug option on an Action.
@@ -18,3 +26,66 @@ type Debugger interface {
 	// setting the output stream to stderr, etc.
 	Debug()
 }
+
+// OutputRecorder is an Action that records the output of the last time it was
+// run.
+type OutputRecorder interface {
+
+	// OutputRecorder is also an Action.
+	Action
+
+	// Output returns the output produced by the last run of the Action.
+	Output() []byte
+}
+
+// OutputStreamer is an Action that can stream its output, line by line, while
+// it runs.
+type OutputStreamer interface {
+
+	// OutputStreamer is also an Action.
+	Action
+
+	// StreamOutput sets the function called with each line of output produced
+	// by subsequent runs of the Action, without its trailing newline. A nil
+	// function stops the output being streamed.
+	StreamOutput(fn func(line string))
+}
+
+// Previewer is an Action that can describe the changes it would make when
+// run, without making them.
+type Previewer interface {
+
+	// Previewer is also an Action.
+	Action
+
+	// Preview returns a human readable description of the changes the Action
+	// would make if it were run, or nil if it would make no changes.
+	Preview(ctx context.Context) ([]byte, error)
+}
+
+// Privileged is an Action that may need to run as root via sudo.
+type Privileged interface {
+
+	// Privileged is also an Action.
+	Action
+
+	// Privileged returns true if running the Action requires sudo
+	// credentials.
+	Privileged() bool
+}
+
+// ExitCode returns the exit code of the process run by an Action, given the
+// error returned from running the Action. Zero is returned if there was no
+// error, and -1 if the error does not carry an exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
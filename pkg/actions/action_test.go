@@ -0,0 +1,16 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Errorf("wanted exit code 0 for no error; got %d", code)
+	}
+
+	if code := ExitCode(errors.New("oh noes")); code != -1 {
+		t.Errorf("wanted exit code -1 for an error without an exit code; got %d", code)
+	}
+}
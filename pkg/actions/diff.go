@@ -0,0 +1,222 @@
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a
+// unified diff
+const diffContext = 3
+
+// maxDiffCells bounds the size of the table used to compute an edit script,
+// as the product of the number of lines in each text, once any lines common
+// to the start and end of both have been set aside. Texts differing by more
+// are not diffed line by line.
+const maxDiffCells = 1 << 22
+
+// diffOp is a single line of an edit script, which is either kept, removed
+// from the old text or inserted into the new text.
+type diffOp struct {
+
+	// kind is one of ' ', '-' or '+'
+	kind byte
+
+	// line is the line, including its trailing newline, if any
+	line string
+}
+
+// unifiedDiff returns a unified diff between the old and new text, with the
+// given names used in the header. Nil is returned if the texts are equal.
+func unifiedDiff(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	ops, ok := editScript(splitLines(old), splitLines(new))
+	if !ok {
+		return []byte(fmt.Sprintf("--- %s\n+++ %s\n%s differs, but is too large to diff\n", oldName, newName, newName))
+	}
+
+	// oldPos[i] and newPos[i] are the number of lines of each text consumed
+	// before the i-th op
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	for i := 0; i < len(ops); {
+		// skip to the next change
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// extend the hunk over any changes separated by a small enough number
+		// of unchanged lines that their context would overlap
+		start := maxInt(i-diffContext, 0)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*diffContext {
+				end = next
+				continue
+			}
+			end = minInt(end+diffContext, len(ops))
+			break
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(oldPos[start], oldPos[end]-oldPos[start]),
+			hunkRange(newPos[start], newPos[end]-newPos[start]))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+
+	return b.Bytes()
+}
+
+// hunkRange formats the range of lines in a hunk header, given the number of
+// lines preceding the hunk and the number of lines in the hunk.
+func hunkRange(start, count int) string {
+	// an empty range refers to the line before the hunk
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits the given text into lines, each including its trailing
+// newline, if any.
+func splitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript returns the shortest sequence of ops that transforms the old
+// lines into the new lines, computed via their longest common subsequence.
+// Removals are ordered before insertions in each change. False is returned if
+// the texts are too large to compare, as bounded by maxDiffCells.
+func editScript(old, new []string) ([]diffOp, bool) {
+	// lines common to the start and end of both texts are kept as they are,
+	// such that a small change to a large text is cheap to compute
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range old[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	changed, ok := lcsScript(old[prefix:len(old)-suffix], new[prefix:len(new)-suffix])
+	if !ok {
+		return nil, false
+	}
+	ops = append(ops, changed...)
+
+	for _, line := range old[len(old)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops, true
+}
+
+// lcsScript returns the edit script of editScript for the given lines, via
+// a table of the lengths of their longest common subsequences. False is
+// returned if the table would be larger than maxDiffCells.
+func lcsScript(old, new []string) ([]diffOp, bool) {
+	if len(old) > 0 && len(new) > maxDiffCells/len(old) {
+		return nil, false
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of old[i:]
+	// and new[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = maxInt(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			ops = append(ops, diffOp{' ', old[i]})
+			i++
+			j++
+		case j == len(new) || (i < len(old) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', old[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', new[j]})
+			j++
+		}
+	}
+	return ops, true
+}
+
+// minInt returns the smaller of a and b.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt returns the larger of a and b.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,103 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff_Equal(t *testing.T) {
+	if diff := unifiedDiff("a", "b", []byte("foo\n"), []byte("foo\n")); diff != nil {
+		t.Errorf("wanted no diff; got %s", diff)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	type testCase struct {
+		old, new string
+		want     string
+	}
+	testCases := []testCase{
+		{
+			old:  "",
+			new:  "foo\nbar\n",
+			want: "@@ -0,0 +1,2 @@\n+foo\n+bar\n",
+		},
+		{
+			old:  "foo\nbar\n",
+			new:  "",
+			want: "@@ -1,2 +0,0 @@\n-foo\n-bar\n",
+		},
+		{
+			old:  "foo\nbar\nbaz\n",
+			new:  "foo\nqux\nbaz\n",
+			want: "@@ -1,3 +1,3 @@\n foo\n-bar\n+qux\n baz\n",
+		},
+		{
+			old:  "foo",
+			new:  "bar",
+			want: "@@ -1 +1 @@\n-foo\n\\ No newline at end of file\n+bar\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		diff := string(unifiedDiff("old", "new", []byte(testCase.old), []byte(testCase.new)))
+		want := "--- old\n+++ new\n" + testCase.want
+		if diff != want {
+			t.Errorf("wanted diff:\n%s\ngot:\n%s", want, diff)
+		}
+	}
+}
+
+func TestUnifiedDiff_Hunks(t *testing.T) {
+	var old, new []string
+	for i := 1; i <= 20; i++ {
+		old = append(old, fmt.Sprintf("%d\n", i))
+		new = append(new, fmt.Sprintf("%d\n", i))
+	}
+	// two changes far enough apart to be in separate hunks
+	new[1] = "two\n"
+	new[17] = "eighteen\n"
+
+	diff := string(unifiedDiff("old", "new", []byte(strings.Join(old, "")), []byte(strings.Join(new, ""))))
+	want := "--- old\n+++ new\n" +
+		"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+		"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+eighteen\n 19\n 20\n"
+	if diff != want {
+		t.Errorf("wanted diff:\n%s\ngot:\n%s", want, diff)
+	}
+
+	// changes whose context overlaps are merged into a single hunk
+	new[17] = "18\n"
+	new[7] = "eight\n"
+	diff = string(unifiedDiff("old", "new", []byte(strings.Join(old, "")), []byte(strings.Join(new, ""))))
+	if strings.Count(diff, "@@ -") != 1 {
+		t.Errorf("wanted a single hunk; got:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiff_Large(t *testing.T) {
+	var old, new []string
+	for i := 0; i < 50000; i++ {
+		old = append(old, fmt.Sprintf("%d\n", i))
+		new = append(new, fmt.Sprintf("%d\n", i))
+	}
+
+	// a small change to a large file is diffed as usual
+	new[25000] = "changed\n"
+	diff := string(unifiedDiff("old", "new", []byte(strings.Join(old, "")), []byte(strings.Join(new, ""))))
+	want := "--- old\n+++ new\n@@ -24998,7 +24998,7 @@\n 24997\n 24998\n 24999\n-25000\n+changed\n 25001\n 25002\n 25003\n"
+	if diff != want {
+		t.Errorf("wanted diff:\n%s\ngot:\n%s", want, diff)
+	}
+
+	// but files differing throughout are not diffed line by line
+	for i := range new {
+		new[i] = fmt.Sprintf("new %d\n", i)
+	}
+	diff = string(unifiedDiff("old", "new", []byte(strings.Join(old, "")), []byte(strings.Join(new, ""))))
+	want = "--- old\n+++ new\nnew differs, but is too large to diff\n"
+	if diff != want {
+		t.Errorf("wanted diff:\n%s\ngot:\n%s", want, diff)
+	}
+}
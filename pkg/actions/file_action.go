@@ -0,0 +1,401 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+const (
+	// defaultFileMode is the mode of files created without an explicit mode
+	defaultFileMode = 0644
+
+	// defaultDirMode is the mode of directories created without an explicit
+	// mode
+	defaultDirMode = 0755
+)
+
+// FileResource is a Resource for a regular file with the given content and
+// permissions.
+type FileResource struct {
+
+	// path is the path to the file
+	path string
+
+	// content is the expected content of the file, or nil for any content
+	content *string
+
+	// mode is the expected permissions of the file, or zero for any
+	// permissions
+	mode os.FileMode
+}
+
+// NewFileResource constructs and returns a new FileResource from the given
+// file.
+func NewFileResource(f *lang.File) *FileResource {
+	return &FileResource{
+		path:    f.Path,
+		content: f.Content,
+		mode:    os.FileMode(f.Mode),
+	}
+}
+
+// Check returns nil if the file exists with the expected content and mode.
+func (r *FileResource) Check(ctx context.Context) error {
+	path, err := expandPath(r.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("file_action: %w", err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("file_action: %s is not a regular file", path)
+	}
+
+	if r.content != nil {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("file_action: %w", err)
+		}
+		if !bytes.Equal(content, []byte(*r.content)) {
+			return fmt.Errorf("file_action: %s has unexpected content", path)
+		}
+	}
+
+	if r.mode != 0 && info.Mode().Perm() != r.mode.Perm() {
+		return fmt.Errorf("file_action: %s has mode %o; want %o", path, info.Mode().Perm(), r.mode.Perm())
+	}
+
+	return nil
+}
+
+// Converge creates or updates the file, along with any parent directories. An
+// error is returned if the path exists, but is not a regular file.
+func (r *FileResource) Converge(ctx context.Context) error {
+	path, err := expandPath(r.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(path), defaultDirMode); err != nil {
+			return fmt.Errorf("file_action: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("file_action: %w", err)
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("file_action: %s exists and is not a regular file", path)
+	}
+
+	mode := r.mode
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+
+	// only write the file if it is missing, or the content is specified
+	if info == nil || r.content != nil {
+		var content []byte
+		if r.content != nil {
+			content = []byte(*r.content)
+		}
+		if err := ioutil.WriteFile(path, content, mode); err != nil {
+			return fmt.Errorf("file_action: %w", err)
+		}
+	}
+
+	// the mode passed to WriteFile is subject to the umask, and the mode of
+	// an existing file is not changed by writing to it
+	if r.mode != 0 {
+		if err := os.Chmod(path, r.mode); err != nil {
+			return fmt.Errorf("file_action: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Diff returns a unified diff between the current and expected content of
+// the file. Nil is returned if the file may have any content.
+func (r *FileResource) Diff(ctx context.Context) ([]byte, error) {
+	if r.content == nil {
+		return nil, nil
+	}
+
+	path, err := expandPath(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := diffFile(path, []byte(*r.content))
+	if err != nil {
+		return nil, fmt.Errorf("file_action: %w", err)
+	}
+	return diff, nil
+}
+
+// String prints a string representation of the file.
+func (r FileResource) String() string {
+	return "[file]: " + r.path
+}
+
+// SymlinkResource is a Resource for a symbolic link pointing to a given path.
+type SymlinkResource struct {
+
+	// src is the path the link points to
+	src string
+
+	// dst is the path of the link
+	dst string
+}
+
+// NewSymlinkResource constructs and returns a new SymlinkResource from the
+// given link.
+func NewSymlinkResource(s *lang.Symlink) *SymlinkResource {
+	return &SymlinkResource{
+		src: s.Src,
+		dst: s.Dst,
+	}
+}
+
+// Check returns nil if the link exists and points to the expected path.
+func (r *SymlinkResource) Check(ctx context.Context) error {
+	src, dst, err := r.paths()
+	if err != nil {
+		return err
+	}
+
+	target, err := os.Readlink(dst)
+	if err != nil {
+		return fmt.Errorf("symlink_action: %w", err)
+	}
+
+	if target != src {
+		return fmt.Errorf("symlink_action: %s points to %s; want %s", dst, target, src)
+	}
+
+	return nil
+}
+
+// Converge creates the link, replacing any existing link at the same path. An
+// error is returned if the path exists, but is not a link.
+func (r *SymlinkResource) Converge(ctx context.Context) error {
+	src, dst, err := r.paths()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(dst)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(dst), defaultDirMode); err != nil {
+			return fmt.Errorf("symlink_action: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("symlink_action: %w", err)
+	case info.Mode()&os.ModeSymlink == 0:
+		return fmt.Errorf("symlink_action: %s exists and is not a symlink", dst)
+	default:
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("symlink_action: %w", err)
+		}
+	}
+
+	if err := os.Symlink(src, dst); err != nil {
+		return fmt.Errorf("symlink_action: %w", err)
+	}
+
+	return nil
+}
+
+// paths returns the expanded source and destination paths of the link.
+func (r *SymlinkResource) paths() (string, string, error) {
+	src, err := expandPath(r.src)
+	if err != nil {
+		return "", "", err
+	}
+
+	dst, err := expandPath(r.dst)
+	if err != nil {
+		return "", "", err
+	}
+
+	return src, dst, nil
+}
+
+// String prints a string representation of the link.
+func (r SymlinkResource) String() string {
+	return "[symlink]: " + r.dst + " -> " + r.src
+}
+
+// DirectoryResource is a Resource for a directory with the given permissions.
+type DirectoryResource struct {
+
+	// path is the path to the directory
+	path string
+
+	// mode is the expected permissions of the directory, or zero for any
+	// permissions
+	mode os.FileMode
+}
+
+// NewDirectoryResource constructs and returns a new DirectoryResource from
+// the given directory.
+func NewDirectoryResource(d *lang.Directory) *DirectoryResource {
+	return &DirectoryResource{
+		path: d.Path,
+		mode: os.FileMode(d.Mode),
+	}
+}
+
+// Check returns nil if the directory exists with the expected mode.
+func (r *DirectoryResource) Check(ctx context.Context) error {
+	path, err := expandPath(r.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("directory_action: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("directory_action: %s is not a directory", path)
+	}
+
+	if r.mode != 0 && info.Mode().Perm() != r.mode.Perm() {
+		return fmt.Errorf("directory_action: %s has mode %o; want %o", path, info.Mode().Perm(), r.mode.Perm())
+	}
+
+	return nil
+}
+
+// Converge creates the directory, along with any parent directories, and sets
+// its mode. An error is returned if the path exists, but is not a directory.
+func (r *DirectoryResource) Converge(ctx context.Context) error {
+	path, err := expandPath(r.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		mode := r.mode
+		if mode == 0 {
+			mode = defaultDirMode
+		}
+		if err := os.MkdirAll(path, mode); err != nil {
+			return fmt.Errorf("directory_action: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("directory_action: %w", err)
+	case !info.IsDir():
+		return fmt.Errorf("directory_action: %s exists and is not a directory", path)
+	}
+
+	// the mode passed to MkdirAll is subject to the umask
+	if r.mode != 0 {
+		if err := os.Chmod(path, r.mode); err != nil {
+			return fmt.Errorf("directory_action: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// String prints a string representation of the directory.
+func (r DirectoryResource) String() string {
+	return "[directory]: " + r.path
+}
+
+// AbsentResource is a Resource for a path that should not exist.
+type AbsentResource struct {
+
+	// path is the path that should not exist
+	path string
+}
+
+// NewAbsentResource constructs and returns a new AbsentResource from the
+// given path.
+func NewAbsentResource(a *lang.Absent) *AbsentResource {
+	return &AbsentResource{path: a.Path}
+}
+
+// Check returns nil if nothing exists at the path.
+func (r *AbsentResource) Check(ctx context.Context) error {
+	path, err := expandPath(r.path)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("absent_action: %w", err)
+	}
+
+	return fmt.Errorf("absent_action: %s exists", path)
+}
+
+// Converge removes the path, and anything it contains.
+func (r *AbsentResource) Converge(ctx context.Context) error {
+	path, err := expandPath(r.path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("absent_action: %w", err)
+	}
+
+	return nil
+}
+
+// String prints a string representation of the path.
+func (r AbsentResource) String() string {
+	return "[absent]: " + r.path
+}
+
+// diffFile returns a unified diff between the current content of the file at
+// the given path and the given content. A missing file is treated as empty.
+func diffFile(path string, content []byte) ([]byte, error) {
+	oldName := path
+	current, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		oldName = os.DevNull
+	} else if err != nil {
+		return nil, err
+	}
+
+	return unifiedDiff(oldName, path, current, content), nil
+}
+
+// expandPath replaces a leading "~" in the given path with the home directory
+// of the current user.
+func expandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not expand %s: %w", path, err)
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
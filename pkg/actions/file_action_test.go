@@ -0,0 +1,220 @@
+package actions
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+func TestFileResource(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "nested", "file")
+	content := "hello"
+
+	r := NewFileResource(&lang.File{Path: path, Content: &content, Mode: 0600})
+	ctx := context.Background()
+
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a missing file")
+	}
+
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != content {
+		t.Errorf("wanted content %q; got %q", content, got)
+	}
+
+	// drift in the content or mode is detected and corrected
+	if err := ioutil.WriteFile(path, []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a modified file")
+	}
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+}
+
+func TestFileResource_AnyContent(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewFileResource(&lang.File{Path: path})
+	if err := r.Check(context.Background()); err != nil {
+		t.Errorf("wanted check to pass for an existing file; got %s", err)
+	}
+}
+
+func TestFileResource_NotRegular(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	r := NewFileResource(&lang.File{Path: dir})
+	if err := r.Check(context.Background()); err == nil {
+		t.Error("wanted check to fail for a directory")
+	}
+	if err := r.Converge(context.Background()); err == nil {
+		t.Error("wanted converge to refuse to replace a directory")
+	}
+}
+
+func TestSymlinkResource(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	src := filepath.Join(dir, "src")
+	other := filepath.Join(dir, "other")
+	dst := filepath.Join(dir, "dst")
+
+	r := NewSymlinkResource(&lang.Symlink{Src: src, Dst: dst})
+	ctx := context.Background()
+
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a missing link")
+	}
+
+	// an existing link pointing elsewhere is replaced
+	if err := os.Symlink(other, dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a link to the wrong target")
+	}
+
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	target, err := os.Readlink(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != src {
+		t.Errorf("wanted link to %s; got %s", src, target)
+	}
+}
+
+func TestSymlinkResource_NotSymlink(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	dst := filepath.Join(dir, "dst")
+	if err := ioutil.WriteFile(dst, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewSymlinkResource(&lang.Symlink{Src: filepath.Join(dir, "src"), Dst: dst})
+	if err := r.Converge(context.Background()); err == nil {
+		t.Error("wanted converge to refuse to replace a regular file")
+	}
+}
+
+func TestDirectoryResource(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "a", "b")
+
+	r := NewDirectoryResource(&lang.Directory{Path: path, Mode: 0700})
+	ctx := context.Background()
+
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a missing directory")
+	}
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("wanted mode 700; got %o", info.Mode().Perm())
+	}
+}
+
+func TestAbsentResource(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "old")
+	if err := os.MkdirAll(filepath.Join(path, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewAbsentResource(&lang.Absent{Path: path})
+	ctx := context.Background()
+
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for an existing path")
+	}
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	testCases := map[string]string{
+		"~":        home,
+		"~/foo":    filepath.Join(home, "foo"),
+		"/foo":     "/foo",
+		"~foo/bar": "~foo/bar",
+	}
+
+	for path, want := range testCases {
+		got, err := expandPath(path)
+		if err != nil {
+			t.Fatalf("did not expect error %s", err)
+		}
+		if got != want {
+			t.Errorf("wanted %s to expand to %s; got %s", path, want, got)
+		}
+	}
+}
+
+// tempDir creates a temporary directory, returning its path and a function
+// that removes it.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "file_action")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
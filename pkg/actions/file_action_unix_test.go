@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package actions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+func TestFileResource_Converge_Umask(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "file")
+	content := "x"
+
+	// a umask that would strip the group write bit of the requested mode
+	old := syscall.Umask(022)
+	defer syscall.Umask(old)
+
+	r := NewFileResource(&lang.File{Path: path, Content: &content, Mode: 0664})
+	ctx := context.Background()
+
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0664 {
+		t.Errorf("wanted mode 664; got %o", mode)
+	}
+
+	if err := r.Check(ctx); err != nil {
+		t.Errorf("wanted check to pass after converging; got %s", err)
+	}
+}
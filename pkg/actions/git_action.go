@@ -0,0 +1,290 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+// GitResource is a Resource for a clone of a git repository, checked out at a
+// given ref. The git CLI must be available on the PATH.
+//
+// Checking the resource only consults the clone itself, without contacting
+// the remote. A branch is therefore considered checked out when HEAD matches
+// the last fetched commit of the remote branch. Converging the resource
+// fetches from the remote before checking out the ref.
+type GitResource struct {
+
+	// url is the URL of the repository
+	url string
+
+	// dest is the path of the clone
+	dest string
+
+	// ref is the branch, tag or commit to check out, or empty for the
+	// default branch
+	ref string
+
+	// env contains variables added to the environment of git
+	env map[string]string
+
+	// sudo determines whether git changes the clone as root via sudo
+	sudo bool
+}
+
+// NewGitResource constructs and returns a new GitResource from the given git
+// repository.
+func NewGitResource(g *lang.Git) *GitResource {
+	return &GitResource{
+		url:  g.URL,
+		dest: g.Dest,
+		ref:  g.Ref,
+	}
+}
+
+// WithEnv returns the GitResource, with the given variables added to the
+// environment of each git command it runs, e.g. GIT_SSH_COMMAND.
+func (r *GitResource) WithEnv(env map[string]string) *GitResource {
+	r.env = env
+	return r
+}
+
+// WithSudo returns the GitResource, cloning, fetching and checking out as
+// root via sudo, e.g. for a clone in a directory owned by root. The clone is
+// still checked as the current user.
+func (r *GitResource) WithSudo() *GitResource {
+	r.sudo = true
+	return r
+}
+
+// Privileged returns true if the clone is changed as root via sudo. A clone
+// is changed directly when the current process is already running as root.
+func (r *GitResource) Privileged() bool {
+	return r.sudo && !isRoot()
+}
+
+// Check returns nil if the destination is a clone of the repository, with the
+// ref checked out.
+func (r *GitResource) Check(ctx context.Context) error {
+	dest, err := expandPath(r.dest)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkOrigin(ctx, dest); err != nil {
+		return err
+	}
+
+	if r.ref == "" {
+		return nil
+	}
+
+	want, err := r.resolveRef(ctx, dest)
+	if err != nil {
+		return err
+	}
+
+	head, err := r.git(ctx, dest, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	if head != want {
+		return fmt.Errorf("git_action: %s is at %s; want %s (%s)", dest, head, r.ref, want)
+	}
+
+	return nil
+}
+
+// Converge clones the repository if the destination does not exist, otherwise
+// fetches from the remote, and then checks out the ref. A destination that
+// exists, but is not a clone of the repository, is left untouched.
+func (r *GitResource) Converge(ctx context.Context) error {
+	dest, err := expandPath(r.dest)
+	if err != nil {
+		return err
+	}
+
+	empty, err := isEmptyDir(dest)
+	if err != nil {
+		return fmt.Errorf("git_action: %w", err)
+	}
+
+	if empty {
+		// git creates the parent of the clone itself, as root if privileged
+		if !r.Privileged() {
+			if err := os.MkdirAll(filepath.Dir(dest), defaultDirMode); err != nil {
+				return fmt.Errorf("git_action: %w", err)
+			}
+		}
+		if _, err := r.change(ctx, "", "clone", "--", r.url, dest); err != nil {
+			return err
+		}
+	} else {
+		if err := r.checkOrigin(ctx, dest); err != nil {
+			return err
+		}
+		if _, err := r.change(ctx, dest, "fetch", "--tags", "origin"); err != nil {
+			return err
+		}
+	}
+
+	if r.ref == "" {
+		return nil
+	}
+
+	// check out branches such that they track the remote branch, and
+	// anything else, i.e. tags and commits, with a detached HEAD
+	if _, err := r.git(ctx, dest, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+r.ref); err == nil {
+		_, err = r.change(ctx, dest, "checkout", "-B", r.ref, "origin/"+r.ref)
+		return err
+	}
+
+	want, err := r.resolveRef(ctx, dest)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.change(ctx, dest, "checkout", "--detach", want)
+	return err
+}
+
+// checkOrigin returns an error if the given path is not a clone of the
+// repository.
+func (r *GitResource) checkOrigin(ctx context.Context, dest string) error {
+	info, err := os.Stat(dest)
+	if err != nil {
+		return fmt.Errorf("git_action: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("git_action: %s is not a directory", dest)
+	}
+
+	// only consider the root of a clone, rather than any directory within it
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err != nil {
+		return fmt.Errorf("git_action: %s is not a clone of %s", dest, r.url)
+	}
+
+	origin, err := r.git(ctx, dest, "remote", "get-url", "origin")
+	if err != nil {
+		return fmt.Errorf("git_action: %s is not a clone of %s: %w", dest, r.url, err)
+	}
+
+	if origin != r.url {
+		return fmt.Errorf("git_action: %s is a clone of %s; want %s", dest, origin, r.url)
+	}
+
+	return nil
+}
+
+// resolveRef returns the commit that the ref refers to in the clone at the
+// given path. Branches are resolved against the remote branch, such that a
+// stale local branch is not mistaken for the ref.
+func (r *GitResource) resolveRef(ctx context.Context, dest string) (string, error) {
+	if commit, err := r.git(ctx, dest, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+r.ref+"^{commit}"); err == nil {
+		return commit, nil
+	}
+
+	commit, err := r.git(ctx, dest, "rev-parse", "--verify", "--quiet", r.ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("git_action: unknown ref %s in %s", r.ref, dest)
+	}
+	return commit, nil
+}
+
+// String prints a string representation of the GitResource.
+func (r GitResource) String() string {
+	if r.ref == "" {
+		return fmt.Sprintf("[git]: %s -> %s", r.url, r.dest)
+	}
+	return fmt.Sprintf("[git]: %s@%s -> %s", r.url, r.ref, r.dest)
+}
+
+// git runs git with the given arguments in the given directory, with the
+// environment of the GitResource, as the current user. As a privileged
+// GitResource clones as root, git is told to trust a clone owned by another
+// user.
+func (r *GitResource) git(ctx context.Context, dir string, args ...string) (string, error) {
+	env := r.env
+	if r.sudo {
+		env = make(map[string]string, len(r.env)+3)
+		for k, v := range r.env {
+			env[k] = v
+		}
+		env["GIT_CONFIG_COUNT"] = "1"
+		env["GIT_CONFIG_KEY_0"] = "safe.directory"
+		env["GIT_CONFIG_VALUE_0"] = "*"
+	}
+	return runGit(ctx, dir, env, false, args...)
+}
+
+// change runs git as for git, but as root via sudo if the GitResource is
+// privileged. It is used for the commands that change the clone.
+func (r *GitResource) change(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGit(ctx, dir, r.env, r.Privileged(), args...)
+}
+
+// git runs git with the given arguments in the given directory, returning its
+// trimmed standard output. The standard error of git is included in the error
+// returned if git fails. Git is prevented from prompting for credentials.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGit(ctx, dir, nil, false, args...)
+}
+
+// runGit runs git as for git, with the given variables added to its
+// environment, and as root via sudo if sudo is true. Variables are passed
+// explicitly via env(1) to git run via sudo, as sudo resets the environment.
+func runGit(ctx context.Context, dir string, env map[string]string, sudo bool, args ...string) (string, error) {
+	vars := append([]string{"GIT_TERMINAL_PROMPT=0"}, variables(env)...)
+
+	var cmd *exec.Cmd
+	if sudo {
+		command := append(append(append([]string{"env"}, vars...), "git"), args...)
+		cmd = exec.CommandContext(ctx, sudoPath, sudoArgs(command...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, "git", args...)
+		cmd.Env = append(os.Environ(), vars...)
+	}
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("git_action: git %s: %w", args[0], ctxErr)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("git_action: git %s: %w", args[0], err)
+		}
+		return "", fmt.Errorf("git_action: git %s: %w: %s", args[0], err, msg)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// isEmptyDir returns true if the given path does not exist, or is an empty
+// directory.
+func isEmptyDir(path string) (bool, error) {
+	entries, err := ioutil.ReadDir(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		// the path may exist, but not be a directory
+		if _, statErr := os.Stat(path); statErr == nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
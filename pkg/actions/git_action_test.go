@@ -0,0 +1,224 @@
+package actions
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+// testRepo is a bare repository, along with a working copy used to push
+// commits to it.
+type testRepo struct {
+	t    *testing.T
+	url  string
+	work string
+}
+
+// newTestRepo creates a bare repository, with a single commit on the main
+// branch, in the given directory.
+func newTestRepo(t *testing.T, dir string) *testRepo {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	bare := filepath.Join(dir, "remote.git")
+	r := &testRepo{t: t, url: "file://" + bare, work: filepath.Join(dir, "work")}
+
+	r.git("", "init", "--quiet", "--bare", bare)
+	r.git("", "init", "--quiet", r.work)
+	r.git(r.work, "checkout", "--quiet", "-b", "main")
+	r.commit("README", "one")
+	r.git(r.work, "remote", "add", "origin", r.url)
+	r.git(r.work, "push", "--quiet", "origin", "main")
+	r.git("", "--git-dir", bare, "symbolic-ref", "HEAD", "refs/heads/main")
+
+	return r
+}
+
+// commit commits a file with the given content to the working copy, returning
+// the commit.
+func (r *testRepo) commit(name, content string) string {
+	if err := ioutil.WriteFile(filepath.Join(r.work, name), []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git(r.work, "add", name)
+	r.git(r.work, "commit", "--quiet", "-m", content)
+	return r.git(r.work, "rev-parse", "HEAD")
+}
+
+// git runs git in the given directory, failing the test on error.
+func (r *testRepo) git(dir string, args ...string) string {
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	out, err := git(context.Background(), dir, args...)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return out
+}
+
+func TestGitResource_Clone(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	repo := newTestRepo(t, dir)
+	dest := filepath.Join(dir, "src", "clone")
+
+	r := NewGitResource(&lang.Git{URL: repo.url, Dest: dest})
+	ctx := context.Background()
+
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a missing clone")
+	}
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dest, "README"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "one" {
+		t.Errorf("wanted README to contain 'one'; got %q", content)
+	}
+}
+
+func TestGitResource_Branch(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	repo := newTestRepo(t, dir)
+	dest := filepath.Join(dir, "clone")
+
+	r := NewGitResource(&lang.Git{URL: repo.url, Dest: dest, Ref: "main"})
+	ctx := context.Background()
+
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	// a new commit on the remote branch is fetched and checked out
+	head := repo.commit("README", "two")
+	repo.git(repo.work, "push", "--quiet", "origin", "main")
+
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	if got := repo.git(dest, "rev-parse", "HEAD"); got != head {
+		t.Errorf("wanted HEAD at %s; got %s", head, got)
+	}
+	if got := repo.git(dest, "rev-parse", "--abbrev-ref", "HEAD"); got != "main" {
+		t.Errorf("wanted branch main checked out; got %s", got)
+	}
+}
+
+func TestGitResource_TagAndCommit(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	repo := newTestRepo(t, dir)
+	first := repo.git(repo.work, "rev-parse", "HEAD")
+	repo.git(repo.work, "tag", "v1")
+	second := repo.commit("README", "two")
+	repo.git(repo.work, "push", "--quiet", "--tags", "origin", "main")
+
+	dest := filepath.Join(dir, "clone")
+	ctx := context.Background()
+
+	for ref, want := range map[string]string{"v1": first, second: second} {
+		r := NewGitResource(&lang.Git{URL: repo.url, Dest: dest, Ref: ref})
+
+		if err := r.Converge(ctx); err != nil {
+			t.Fatalf("did not expect error %s", err)
+		}
+		if err := r.Check(ctx); err != nil {
+			t.Fatalf("wanted check to pass after converging; got %s", err)
+		}
+		if got := repo.git(dest, "rev-parse", "HEAD"); got != want {
+			t.Errorf("wanted HEAD at %s for ref %s; got %s", want, ref, got)
+		}
+	}
+}
+
+func TestGitResource_WrongRef(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	repo := newTestRepo(t, dir)
+	dest := filepath.Join(dir, "clone")
+	ctx := context.Background()
+
+	if err := NewGitResource(&lang.Git{URL: repo.url, Dest: dest}).Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	// an unknown ref is not met, and cannot be converged
+	r := NewGitResource(&lang.Git{URL: repo.url, Dest: dest, Ref: "missing"})
+	if err := r.Check(ctx); err == nil {
+		t.Error("wanted check to fail for an unknown ref")
+	}
+	if err := r.Converge(ctx); err == nil {
+		t.Error("wanted converge to fail for an unknown ref")
+	}
+}
+
+func TestGitResource_NotAClone(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	repo := newTestRepo(t, dir)
+	dest := filepath.Join(dir, "existing")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dest, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewGitResource(&lang.Git{URL: repo.url, Dest: dest})
+	if err := r.Check(context.Background()); err == nil {
+		t.Error("wanted check to fail for a directory that is not a clone")
+	}
+	if err := r.Converge(context.Background()); err == nil {
+		t.Error("wanted converge to refuse to touch a directory that is not a clone")
+	}
+
+	// a clone of another repository is also left untouched
+	other := NewGitResource(&lang.Git{URL: repo.url + ".other", Dest: repo.work})
+	if err := other.Converge(context.Background()); err == nil {
+		t.Error("wanted converge to refuse to touch a clone of another repository")
+	}
+}
+
+func TestGitResource_WithEnv(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	repo := newTestRepo(t, dir)
+	dest := filepath.Join(dir, "clone")
+	ctx := context.Background()
+
+	if err := NewGitResource(&lang.Git{URL: repo.url, Dest: dest}).Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	// git is run with the given environment, here pointing it away from the
+	// clone
+	r := NewGitResource(&lang.Git{URL: repo.url, Dest: dest}).WithEnv(map[string]string{
+		"GIT_DIR": filepath.Join(dir, "missing"),
+	})
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail with GIT_DIR pointing away from the clone")
+	}
+}
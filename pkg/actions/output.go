@@ -0,0 +1,112 @@
+package actions
+
+import "bytes"
+
+// maxOutputBytes is the maximum number of bytes of output retained for each
+// run of an Action.
+const maxOutputBytes = 64 * 1024
+
+// tailBuffer is an io.Writer that retains only the last limit bytes written
+// to it. Older output is discarded as new output is written.
+type tailBuffer struct {
+
+	// limit is the maximum number of bytes to retain
+	limit int
+
+	// buf contains the retained bytes
+	buf []byte
+
+	// truncated determines whether any output has been discarded
+	truncated bool
+}
+
+// newTailBuffer returns a new tailBuffer retaining at most limit bytes.
+func newTailBuffer(limit int) *tailBuffer {
+	return &tailBuffer{limit: limit}
+}
+
+// Write appends the given bytes to the buffer, discarding the oldest bytes if
+// the buffer would exceed its limit. Write never returns an error.
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if len(p) >= b.limit {
+		b.truncated = b.truncated || len(b.buf) > 0 || len(p) > b.limit
+		b.buf = append(b.buf[:0], p[len(p)-b.limit:]...)
+		return n, nil
+	}
+
+	if overflow := len(b.buf) + len(p) - b.limit; overflow > 0 {
+		b.truncated = true
+		b.buf = append(b.buf[:0], b.buf[overflow:]...)
+	}
+	b.buf = append(b.buf, p...)
+
+	return n, nil
+}
+
+// Bytes returns the retained bytes.
+func (b *tailBuffer) Bytes() []byte {
+	return b.buf
+}
+
+// Len returns the number of retained bytes.
+func (b *tailBuffer) Len() int {
+	return len(b.buf)
+}
+
+// Truncated returns true if any output written to the buffer was discarded.
+func (b *tailBuffer) Truncated() bool {
+	return b.truncated
+}
+
+// Reset empties the buffer.
+func (b *tailBuffer) Reset() {
+	b.buf = b.buf[:0]
+	b.truncated = false
+}
+
+// lineWriter is an io.Writer that calls a function with each complete line
+// written to it, without its trailing newline.
+type lineWriter struct {
+
+	// fn is called with each line
+	fn func(line string)
+
+	// partial is the last line written, until it is completed by a newline
+	partial []byte
+}
+
+// newLineWriter returns a new lineWriter calling the given function.
+func newLineWriter(fn func(line string)) *lineWriter {
+	return &lineWriter{fn: fn}
+}
+
+// Write calls the function with each line completed by the given bytes,
+// retaining any partial line until it is completed. Write never returns an
+// error.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(string(append(w.partial, p[:i]...)))
+		w.partial = w.partial[:0]
+		p = p[i+1:]
+	}
+	w.partial = append(w.partial, p...)
+
+	return n, nil
+}
+
+// Flush calls the function with the partial line retained, if any.
+func (w *lineWriter) Flush() {
+	if len(w.partial) == 0 {
+		return
+	}
+	w.fn(string(w.partial))
+	w.partial = w.partial[:0]
+}
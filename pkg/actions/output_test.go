@@ -0,0 +1,85 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTailBuffer_Write_WithinLimit(t *testing.T) {
+	b := newTailBuffer(8)
+
+	_, _ = b.Write([]byte("foo"))
+	_, _ = b.Write([]byte("bar"))
+
+	if string(b.Bytes()) != "foobar" {
+		t.Errorf("wanted 'foobar'; got '%s'", b.Bytes())
+	}
+
+	if b.Truncated() {
+		t.Error("wanted buffer not truncated")
+	}
+}
+
+func TestTailBuffer_Write_ExceedsLimit(t *testing.T) {
+	b := newTailBuffer(4)
+
+	n, err := b.Write([]byte("foo"))
+	if n != 3 || err != nil {
+		t.Fatalf("wanted 3 bytes written without error; got %d, %+v", n, err)
+	}
+	_, _ = b.Write([]byte("bar"))
+
+	if string(b.Bytes()) != "obar" {
+		t.Errorf("wanted 'obar'; got '%s'", b.Bytes())
+	}
+
+	if !b.Truncated() {
+		t.Error("wanted buffer truncated")
+	}
+}
+
+func TestTailBuffer_Write_LargerThanLimit(t *testing.T) {
+	b := newTailBuffer(4)
+
+	n, _ := b.Write([]byte("foobarbaz"))
+	if n != 9 {
+		t.Errorf("wanted 9 bytes written; got %d", n)
+	}
+
+	if string(b.Bytes()) != "rbaz" {
+		t.Errorf("wanted 'rbaz'; got '%s'", b.Bytes())
+	}
+
+	if !b.Truncated() {
+		t.Error("wanted buffer truncated")
+	}
+}
+
+func TestTailBuffer_Reset(t *testing.T) {
+	b := newTailBuffer(2)
+	_, _ = b.Write([]byte("foo"))
+
+	b.Reset()
+
+	if b.Len() != 0 || b.Truncated() {
+		t.Errorf("wanted empty buffer; got '%s'", b.Bytes())
+	}
+}
+
+func TestLineWriter_Write(t *testing.T) {
+	var lines []string
+	w := newLineWriter(func(line string) {
+		lines = append(lines, line)
+	})
+
+	_, _ = w.Write([]byte("foo\nba"))
+	_, _ = w.Write([]byte("r\n\nbaz"))
+	if got := strings.Join(lines, "|"); got != "foo|bar|" {
+		t.Errorf("wanted lines 'foo|bar|'; got '%s'", got)
+	}
+
+	w.Flush()
+	if got := strings.Join(lines, "|"); got != "foo|bar||baz" {
+		t.Errorf("wanted lines 'foo|bar||baz'; got '%s'", got)
+	}
+}
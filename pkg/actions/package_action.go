@@ -0,0 +1,244 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+// PackageManager is a backend for querying and installing packages with a
+// package manager on the local system.
+type PackageManager interface {
+
+	// Name returns the name of the package manager, e.g. "apt". The name is
+	// used to look up the name of a package specific to this manager.
+	Name() string
+
+	// Available returns true if the package manager can be used on the local
+	// system.
+	Available() bool
+
+	// Installed returns true if the given package is installed.
+	Installed(ctx context.Context, pkg string) (bool, error)
+
+	// Install installs the given package.
+	Install(ctx context.Context, pkg string) error
+}
+
+// packageManagers are the supported package managers, in the order in which
+// they are preferred when more than one is available.
+var packageManagers = []PackageManager{
+	&commandManager{
+		name:       "apt",
+		privileged: true,
+		binary:     "apt-get",
+		query:      []string{"dpkg-query", "--show", "--showformat=${Status}"},
+		install:    []string{"apt-get", "install", "--yes", "--quiet"},
+		// removed packages may still be known to dpkg
+		installed: func(out string) bool { return strings.HasSuffix(out, " installed") },
+	},
+	&commandManager{
+		name:       "dnf",
+		privileged: true,
+		binary:     "dnf",
+		query:      []string{"rpm", "--query"},
+		install:    []string{"dnf", "install", "--assumeyes", "--quiet"},
+	},
+	&commandManager{
+		name:       "pacman",
+		privileged: true,
+		binary:     "pacman",
+		query:      []string{"pacman", "--query", "--info"},
+		install:    []string{"pacman", "--sync", "--needed", "--noconfirm"},
+	},
+	&commandManager{
+		name:    "brew",
+		binary:  "brew",
+		query:   []string{"brew", "list", "--versions"},
+		install: []string{"brew", "install"},
+		// brew exits successfully with no output for missing packages
+		installed: func(out string) bool { return out != "" },
+	},
+}
+
+// DetectPackageManager returns the first supported package manager that is
+// available on the local system, or nil if there is none.
+func DetectPackageManager() PackageManager {
+	for _, manager := range packageManagers {
+		if manager.Available() {
+			return manager
+		}
+	}
+	return nil
+}
+
+// PackageResource is a Resource for a package installed by a package manager.
+type PackageResource struct {
+
+	// pkg is the package
+	pkg *lang.Package
+
+	// manager is the package manager used to query and install the package,
+	// or nil if there is no package manager available
+	manager PackageManager
+}
+
+// NewPackageResource constructs and returns a new PackageResource from the
+// given package, which is queried and installed with the given package
+// manager.
+func NewPackageResource(p *lang.Package, manager PackageManager) *PackageResource {
+	return &PackageResource{
+		pkg:     p,
+		manager: manager,
+	}
+}
+
+// Check returns nil if the package is installed.
+func (r *PackageResource) Check(ctx context.Context) error {
+	if r.manager == nil {
+		return fmt.Errorf("package_action: no supported package manager found")
+	}
+
+	name := r.pkg.NameFor(r.manager.Name())
+	installed, err := r.manager.Installed(ctx, name)
+	if err != nil {
+		return fmt.Errorf("package_action: %w", err)
+	}
+
+	if !installed {
+		return fmt.Errorf("package_action: %s is not installed with %s", name, r.manager.Name())
+	}
+
+	return nil
+}
+
+// Converge installs the package.
+func (r *PackageResource) Converge(ctx context.Context) error {
+	if r.manager == nil {
+		return fmt.Errorf("package_action: no supported package manager found")
+	}
+
+	if err := r.manager.Install(ctx, r.pkg.NameFor(r.manager.Name())); err != nil {
+		return fmt.Errorf("package_action: %w", err)
+	}
+
+	return nil
+}
+
+// Privileged returns true if installing the package requires sudo
+// credentials.
+func (r *PackageResource) Privileged() bool {
+	return privileged(r.manager)
+}
+
+// String prints a string representation of the PackageResource.
+func (r PackageResource) String() string {
+	if r.manager == nil {
+		return "[pkg]: " + r.pkg.Name
+	}
+	return fmt.Sprintf("[%s]: %s", r.manager.Name(), r.pkg.NameFor(r.manager.Name()))
+}
+
+// commandManager is a PackageManager that queries and installs packages by
+// running commands, with the name of the package as the last argument.
+type commandManager struct {
+
+	// name is the name of the package manager
+	name string
+
+	// binary is the binary that must be on the PATH for the package manager
+	// to be available
+	binary string
+
+	// query is the command that exits successfully if a package is installed
+	query []string
+
+	// install is the command that installs a package
+	install []string
+
+	// installed optionally checks the output of a successful query for
+	// whether the package is installed
+	installed func(out string) bool
+
+	// privileged determines whether packages are installed as root via sudo
+	privileged bool
+}
+
+// Name returns the name of the package manager.
+func (m *commandManager) Name() string {
+	return m.name
+}
+
+// Available returns true if the binary of the package manager is on the PATH.
+func (m *commandManager) Available() bool {
+	_, err := exec.LookPath(m.binary)
+	return err == nil
+}
+
+// Installed runs the query command for the package. A query that fails with
+// an exit code is taken to mean the package is not installed.
+func (m *commandManager) Installed(ctx context.Context, pkg string) (bool, error) {
+	out, err := runCommand(ctx, withArg(m.query, pkg))
+	if err != nil {
+		if ExitCode(err) > 0 {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if m.installed != nil {
+		return m.installed(out), nil
+	}
+	return true, nil
+}
+
+// Install runs the install command for the package, via sudo if the package
+// manager is privileged.
+func (m *commandManager) Install(ctx context.Context, pkg string) error {
+	command := withArg(m.install, pkg)
+	if m.Privileged() {
+		command = append([]string{sudoPath}, sudoArgs(command...)...)
+	}
+
+	_, err := runCommand(ctx, command)
+	return err
+}
+
+// Privileged returns true if installing packages requires sudo credentials.
+func (m *commandManager) Privileged() bool {
+	return m.privileged && !isRoot()
+}
+
+// runCommand runs the given command, returning its trimmed standard output.
+// The standard error of the command is included in the error returned if the
+// command fails.
+func runCommand(ctx context.Context, args []string) (string, error) {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("%s: %w", args[0], ctxErr)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("%s: %w", args[0], err)
+		}
+		return "", fmt.Errorf("%s: %w: %s", args[0], err, msg)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// withArg returns a copy of the given command with the given argument
+// appended.
+func withArg(command []string, arg string) []string {
+	return append(append([]string{}, command...), arg)
+}
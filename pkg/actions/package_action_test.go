@@ -0,0 +1,136 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+// fakeManager is a PackageManager that records installed packages in memory.
+type fakeManager struct {
+	installed map[string]bool
+	err       error
+}
+
+func (m *fakeManager) Name() string { return "fake" }
+
+func (m *fakeManager) Available() bool { return true }
+
+func (m *fakeManager) Installed(ctx context.Context, pkg string) (bool, error) {
+	return m.installed[pkg], m.err
+}
+
+func (m *fakeManager) Install(ctx context.Context, pkg string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.installed[pkg] = true
+	return nil
+}
+
+func TestPackageResource(t *testing.T) {
+	manager := &fakeManager{installed: map[string]bool{}}
+	p := &lang.Package{Name: "fd", Names: map[string]string{"fake": "fd-find"}}
+
+	r := NewPackageResource(p, manager)
+	ctx := context.Background()
+
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a missing package")
+	}
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	// the name specific to the package manager is used
+	if !manager.installed["fd-find"] {
+		t.Errorf("wanted fd-find installed; got %+v", manager.installed)
+	}
+
+	if r.String() != "[fake]: fd-find" {
+		t.Errorf("wanted [fake]: fd-find; got %s", r)
+	}
+}
+
+func TestPackageResource_ManagerError(t *testing.T) {
+	manager := &fakeManager{err: errors.New("locked")}
+	r := NewPackageResource(&lang.Package{Name: "fd"}, manager)
+
+	if err := r.Check(context.Background()); !errors.Is(err, manager.err) {
+		t.Errorf("wanted error %s; got %v", manager.err, err)
+	}
+	if err := r.Converge(context.Background()); !errors.Is(err, manager.err) {
+		t.Errorf("wanted error %s; got %v", manager.err, err)
+	}
+}
+
+func TestPackageResource_NoManager(t *testing.T) {
+	r := NewPackageResource(&lang.Package{Name: "fd"}, nil)
+
+	if err := r.Check(context.Background()); err == nil {
+		t.Error("wanted check to fail without a package manager")
+	}
+	if err := r.Converge(context.Background()); err == nil {
+		t.Error("wanted converge to fail without a package manager")
+	}
+}
+
+func TestCommandManager(t *testing.T) {
+	manager := &commandManager{
+		name:    "sh",
+		binary:  "sh",
+		query:   []string{"sh", "-c", `test "$0" = installed`},
+		install: []string{"sh", "-c", `test "$0" = installable`},
+	}
+	ctx := context.Background()
+
+	if !manager.Available() {
+		t.Fatal("wanted sh to be available")
+	}
+
+	installed, err := manager.Installed(ctx, "installed")
+	if err != nil || !installed {
+		t.Errorf("wanted package installed; got %t, %v", installed, err)
+	}
+
+	installed, err = manager.Installed(ctx, "missing")
+	if err != nil || installed {
+		t.Errorf("wanted package not installed; got %t, %v", installed, err)
+	}
+
+	if err := manager.Install(ctx, "installable"); err != nil {
+		t.Errorf("did not expect error %s", err)
+	}
+	if err := manager.Install(ctx, "missing"); err == nil {
+		t.Error("wanted install to fail")
+	}
+}
+
+func TestCommandManager_InstalledOutput(t *testing.T) {
+	manager := &commandManager{
+		query:     []string{"echo"},
+		installed: func(out string) bool { return out == "installed" },
+	}
+
+	installed, err := manager.Installed(context.Background(), "installed")
+	if err != nil || !installed {
+		t.Errorf("wanted package installed; got %t, %v", installed, err)
+	}
+
+	installed, err = manager.Installed(context.Background(), "deinstalled")
+	if err != nil || installed {
+		t.Errorf("wanted package not installed; got %t, %v", installed, err)
+	}
+}
+
+func TestCommandManager_Unavailable(t *testing.T) {
+	manager := &commandManager{binary: "matryoshka-missing-binary"}
+	if manager.Available() {
+		t.Error("did not expect a missing binary to be available")
+	}
+}
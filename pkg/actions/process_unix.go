@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package actions
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup configures the command to run in a new process group, such
+// that the command and any processes it starts can be killed together.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group of the given running command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// terminateProcessGroup asks the process group of the given running command
+// to terminate.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+// +build !windows
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShellCommandAction_Run_Timeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shell_action")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	marker := filepath.Join(dir, "marker")
+
+	// the command starts a child process that outlives the shell, and writes
+	// the marker file, unless the whole process group is killed
+	cmd := newCommand(fmt.Sprintf("(sleep 1; touch %s) & wait", marker))
+	cmd.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err = cmd.Run(context.Background())
+	if err == nil {
+		t.Fatal("wanted command to time out")
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wanted error to wrap context.DeadlineExceeded; got %+v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("wanted command to stop at the timeout; ran for %s", elapsed)
+	}
+
+	// wait long enough for the child to have written the marker, had it not
+	// been killed
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("wanted child process to be killed before writing %s", marker)
+	}
+}
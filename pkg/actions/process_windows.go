@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package actions
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing, as process groups are not supported.
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// killProcessGroup kills the given running command. Any processes started by
+// the command are not killed.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// terminateProcessGroup kills the given running command, as there is no way
+// to ask it to terminate.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
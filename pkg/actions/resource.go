@@ -0,0 +1,96 @@
+package actions
+
+import (
+	"context"
+)
+
+// Resource is some state on the local system, such as a file or a directory,
+// that knows both how to check whether it is present, and how to converge the
+// system towards it.
+type Resource interface {
+
+	// Check returns nil if the resource is in its desired state, otherwise an
+	// error describing how it differs.
+	Check(ctx context.Context) error
+
+	// Converge attempts to bring the resource into its desired state,
+	// returning an error if it could not.
+	Converge(ctx context.Context) error
+
+	// String returns a short, human readable description of the resource.
+	String() string
+}
+
+// Differ is a Resource that can describe how its current state differs from
+// its desired state.
+type Differ interface {
+	Resource
+
+	// Diff returns a human readable description of the changes required to
+	// bring the resource into its desired state, or nil if there are none.
+	Diff(ctx context.Context) ([]byte, error)
+}
+
+// NewCheckAction returns an Action that checks the given Resource. The Action
+// is suitable for use as a "met" action.
+func NewCheckAction(r Resource) Action {
+	return &checkAction{r}
+}
+
+// NewConvergeAction returns an Action that converges the given Resource. The
+// Action is suitable for use as a "meet" action.
+func NewConvergeAction(r Resource) Action {
+	return &convergeAction{r}
+}
+
+// checkAction is an Action that checks a Resource.
+type checkAction struct {
+	resource Resource
+}
+
+// Run checks the Resource.
+func (a *checkAction) Run(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.resource.Check(ctx)
+}
+
+// String prints a string representation of the Resource.
+func (a checkAction) String() string {
+	return a.resource.String()
+}
+
+// convergeAction is an Action that converges a Resource.
+type convergeAction struct {
+	resource Resource
+}
+
+// Run converges the Resource.
+func (a *convergeAction) Run(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.resource.Converge(ctx)
+}
+
+// String prints a string representation of the Resource.
+func (a convergeAction) String() string {
+	return a.resource.String()
+}
+
+// Preview returns the changes that converging the Resource would make, if the
+// Resource is a Differ. Otherwise nil is returned.
+func (a *convergeAction) Preview(ctx context.Context) ([]byte, error) {
+	differ, ok := a.resource.(Differ)
+	if !ok {
+		return nil, nil
+	}
+	return differ.Diff(ctx)
+}
+
+// Privileged returns true if converging the Resource requires sudo
+// credentials.
+func (a *convergeAction) Privileged() bool {
+	return privileged(a.resource)
+}
@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeResource struct {
+	checked, converged int
+}
+
+func (r *fakeResource) Check(ctx context.Context) error {
+	r.checked++
+	return nil
+}
+
+func (r *fakeResource) Converge(ctx context.Context) error {
+	r.converged++
+	return nil
+}
+
+func (r *fakeResource) String() string { return "[fake]" }
+
+func TestResourceActions(t *testing.T) {
+	r := &fakeResource{}
+
+	if err := NewCheckAction(r).Run(context.Background()); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := NewConvergeAction(r).Run(context.Background()); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if r.checked != 1 || r.converged != 1 {
+		t.Errorf("wanted one check and one converge; got %d and %d", r.checked, r.converged)
+	}
+
+	if s := NewCheckAction(r).(interface{ String() string }).String(); s != "[fake]" {
+		t.Errorf("wanted action to be described as [fake]; got %s", s)
+	}
+}
+
+func TestResourceActions_Cancelled(t *testing.T) {
+	r := &fakeResource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, action := range []Action{NewCheckAction(r), NewConvergeAction(r)} {
+		if err := action.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("wanted context.Canceled; got %v", err)
+		}
+	}
+	if r.checked != 0 || r.converged != 0 {
+		t.Errorf("did not expect resource to be touched; got %d checks and %d converges", r.checked, r.converged)
+	}
+}
@@ -1,34 +1,67 @@
 package actions
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"time"
 
 	"github.com/nicktrav/matryoshka/pkg/lang"
 )
 
-// ShellCommandAction is an Action that will run a command using a Bourne shell (i.e. `sh`)
-// TODO(nickt): support varargs, i.e. sh(foo, bar, baz), will run "sh -c foo bar baz"
-// TODO(nickt): Handle sudo
+// ShellCommandAction is an Action that will run a command using a Bourne shell (i.e. `sh`),
+// or run a program directly with an argument vector.
 type ShellCommandAction struct {
 
 	// command is the shell command to run
 	command string
 
+	// argv is the argument vector to run directly, or empty to run the
+	// command in a shell
+	argv []string
+
 	// shell is the type of shell to run
 	shell string
 
 	// login determines whether the shell should be a login shell
 	login bool
 
+	// timeout is the maximum length of time the command may run for, or zero
+	// if there is no limit
+	timeout time.Duration
+
+	// env contains variables added to the environment of the command
+	env map[string]string
+
+	// cwd is the working directory of the command, or empty for the working
+	// directory of the current process
+	cwd string
+
+	// clearEnv determines whether the command starts with an empty
+	// environment, rather than the environment of the current process
+	clearEnv bool
+
+	// sudo determines whether the command runs as root via sudo
+	sudo bool
+
 	// outputWriter is a writer to use for capturing stdout and stderr
 	outputWriter io.Writer
 
 	// debug determines whether the command will output debug information to
 	// the outputWriter
 	debug bool
+
+	// output is the tail of the combined stdout and stderr of the last run of
+	// the command
+	output *tailBuffer
+
+	// stream, if set, is called with each line of the combined stdout and
+	// stderr as the command runs
+	stream func(line string)
 }
 
 // NewShellCommandAction constructs and returns a new ShellCommandAction
@@ -36,41 +69,228 @@ type ShellCommandAction struct {
 func NewShellCommandAction(cmd *lang.ShellCmd) *ShellCommandAction {
 	return &ShellCommandAction{
 		command:      cmd.Command,
+		argv:         cmd.Argv,
 		shell:        cmd.Shell,
 		login:        cmd.Login,
+		timeout:      cmd.Timeout,
+		env:          cmd.Env,
+		cwd:          cmd.Cwd,
+		clearEnv:     cmd.ClearEnv,
+		sudo:         cmd.Sudo,
 		outputWriter: os.Stderr,
+		output:       newTailBuffer(maxOutputBytes),
 	}
 }
 
-// Run executes the command as a Shell sub-process. If the command cannot be run,
-// the error is returned.
-func (s *ShellCommandAction) Run() error {
-	var args []string
-	if s.login {
-		args = append(args, "-l")
+// Run executes the command as a Shell sub-process, or runs the program of the
+// argument vector directly. If the command cannot be run, the error is
+// returned.
+//
+// The command runs in its own process group. If the context is done, or the
+// command exceeds its timeout, the entire process group is killed, and the
+// error returned wraps the error from the context.
+//
+// A command that runs via sudo does not inherit the environment of the
+// current process, as sudo resets the environment. Only the variables given
+// to the command are set. As the command runs as root, it is first asked to
+// terminate via sudo, and only killed if it does not exit in time.
+func (s *ShellCommandAction) Run(ctx context.Context) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
 	}
-	args = append(args, "-c", s.command)
 
-	cmd := exec.Command(s.shell, args...)
+	args := s.args()
 
+	var cmd *exec.Cmd
+	var grace time.Duration
+	if s.Privileged() {
+		// variables are passed explicitly via env(1), as sudo resets the
+		// environment
+		envArgs := []string{"env"}
+		if s.clearEnv {
+			envArgs = append(envArgs, "-i")
+		}
+		envArgs = append(envArgs, s.variables()...)
+		cmd = exec.Command(sudoPath, sudoArgs(append(envArgs, args...)...)...)
+		grace = sudoGrace
+	} else {
+		cmd = exec.Command(args[0], args[1:]...)
+		cmd.Env = s.environ()
+	}
+	if s.cwd != "" {
+		cwd, err := expandPath(s.cwd)
+		if err != nil {
+			return err
+		}
+		cmd.Dir = cwd
+	}
+	setProcessGroup(cmd)
+
+	// the tail of the output of the command is always captured. When
+	// debugging, the full output is also written in one go once the command
+	// completes, so that the output of commands running concurrently is not
+	// interleaved
+	s.output.Reset()
+	writers := []io.Writer{s.output}
+	var debugOutput bytes.Buffer
 	if s.debug {
-		cmd.Stdout = s.outputWriter
-		cmd.Stderr = s.outputWriter
+		writers = append(writers, &debugOutput)
+	}
+	var lines *lineWriter
+	if s.stream != nil {
+		lines = newLineWriter(s.stream)
+		writers = append(writers, lines)
+	}
+	if len(writers) == 1 {
+		cmd.Stdout = s.output
+	} else {
+		cmd.Stdout = io.MultiWriter(writers...)
+	}
+	cmd.Stderr = cmd.Stdout
+
+	err := run(ctx, cmd, grace)
+	if lines != nil {
+		lines.Flush()
+	}
+
+	if debugOutput.Len() > 0 {
+		if _, werr := s.outputWriter.Write(debugOutput.Bytes()); werr != nil {
+			return fmt.Errorf("shell_action: %s", werr)
+		}
 	}
 
-	err := cmd.Run()
 	if err != nil {
-		return fmt.Errorf("shell_action: %s", err)
+		return fmt.Errorf("shell_action: %w", err)
 	}
 
 	return nil
 }
 
+// args returns the program to run, followed by its arguments. This is either
+// the argument vector, or the shell with the command.
+func (s *ShellCommandAction) args() []string {
+	if len(s.argv) > 0 {
+		return s.argv
+	}
+
+	args := []string{s.shell}
+	if s.login {
+		args = append(args, "-l")
+	}
+	return append(args, "-c", s.command)
+}
+
+// environ returns the environment of the command, in the form accepted by
+// exec.Cmd. Nil is returned if the command inherits the environment of the
+// current process unchanged.
+func (s *ShellCommandAction) environ() []string {
+	if !s.clearEnv && len(s.env) == 0 {
+		return nil
+	}
+
+	// an empty, non-nil environment is required to clear the environment
+	env := []string{}
+	if !s.clearEnv {
+		env = append(env, os.Environ()...)
+	}
+
+	// later variables take precedence over earlier variables of the same name
+	return append(env, s.variables()...)
+}
+
+// variables returns the variables given to the command, in the form accepted
+// by exec.Cmd, sorted by name.
+func (s *ShellCommandAction) variables() []string {
+	return variables(s.env)
+}
+
+// variables returns the given variables, in the form accepted by exec.Cmd,
+// sorted by name.
+func variables(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var variables []string
+	for _, k := range keys {
+		variables = append(variables, k+"="+env[k])
+	}
+	return variables
+}
+
+// run starts the given command and waits for it to complete. If the context
+// is done before the command completes, the process group of the command is
+// killed and the error from the context is returned. If grace is non-zero, the
+// process group is first asked to terminate, and is only killed if the command
+// does not complete within the grace period.
+func run(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if grace > 0 {
+			_ = terminateProcessGroup(cmd)
+
+			timer := time.NewTimer(grace)
+			defer timer.Stop()
+
+			select {
+			case <-done:
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		_ = killProcessGroup(cmd)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Privileged returns true if the command runs as root via sudo. A command is
+// run directly when the current process is already running as root.
+func (s *ShellCommandAction) Privileged() bool {
+	return s.sudo && !isRoot()
+}
+
+// Output returns the tail of the combined stdout and stderr of the last run of
+// the command.
+func (s *ShellCommandAction) Output() []byte {
+	return s.output.Bytes()
+}
+
+// StreamOutput sets the function called with each line of the combined stdout
+// and stderr of the command, as it runs. The function is never called
+// concurrently.
+func (s *ShellCommandAction) StreamOutput(fn func(line string)) {
+	s.stream = fn
+}
+
 func (s *ShellCommandAction) Debug() {
 	s.debug = true
 }
 
 // String prints a string representation of the current command
 func (s ShellCommandAction) String() string {
+	if len(s.argv) > 0 {
+		return "[exec]: " + s.command
+	}
 	return "[sh]: " + s.command
 }
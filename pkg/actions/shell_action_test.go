@@ -2,6 +2,10 @@ package actions
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -11,7 +15,7 @@ import (
 func TestShellCommandAction_Run_Success(t *testing.T) {
 	cmd := newCommand("echo foo")
 
-	err := cmd.Run()
+	err := cmd.Run(context.Background())
 	if err != nil {
 		t.Errorf("command failed: %s", err)
 	}
@@ -28,7 +32,7 @@ func TestShellCommandAction_Run_Debug(t *testing.T) {
 	// enable debugging
 	cmd.Debug()
 
-	err := cmd.Run()
+	err := cmd.Run(context.Background())
 	if err != nil {
 		t.Errorf("command failed: %s", err)
 	}
@@ -42,7 +46,7 @@ func TestShellCommandAction_Run_Debug(t *testing.T) {
 func TestShellCommandAction_Run_Fail(t *testing.T) {
 	cmd := newCommand("false")
 
-	err := cmd.Run()
+	err := cmd.Run(context.Background())
 	if err == nil {
 		t.Fatal("wanted command to fail with non-zero exit code")
 	}
@@ -53,6 +57,143 @@ func TestShellCommandAction_Run_Fail(t *testing.T) {
 	}
 }
 
+func TestShellCommandAction_Output(t *testing.T) {
+	cmd := newCommand("echo foo; echo bar >&2; exit 3")
+
+	err := cmd.Run(context.Background())
+	if err == nil {
+		t.Fatal("wanted command to fail with non-zero exit code")
+	}
+
+	want := "foo\nbar\n"
+	if string(cmd.Output()) != want {
+		t.Errorf("wanted output '%s'; got '%s'", want, cmd.Output())
+	}
+
+	if code := ExitCode(err); code != 3 {
+		t.Errorf("wanted exit code 3; got %d", code)
+	}
+}
+
+func TestShellCommandAction_StreamOutput(t *testing.T) {
+	cmd := newCommand("echo foo; echo bar >&2; printf baz")
+
+	var lines []string
+	cmd.StreamOutput(func(line string) {
+		lines = append(lines, line)
+	})
+
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("command failed: %s", err)
+	}
+
+	if got := strings.Join(lines, "|"); got != "foo|bar|baz" {
+		t.Errorf("wanted lines 'foo|bar|baz'; got '%s'", got)
+	}
+
+	if string(cmd.Output()) != "foo\nbar\nbaz" {
+		t.Errorf("wanted output recorded; got '%s'", cmd.Output())
+	}
+}
+
+func TestShellCommandAction_Run_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cmd := newCommand("echo foo")
+	err := cmd.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted error to wrap context.Canceled; got %+v", err)
+	}
+
+	if len(cmd.Output()) != 0 {
+		t.Errorf("wanted command not to run; got output '%s'", cmd.Output())
+	}
+}
+
+func TestShellCommandAction_Run_Env(t *testing.T) {
+	os.Setenv("MATRYOSHKA_INHERITED", "inherited")
+	defer os.Unsetenv("MATRYOSHKA_INHERITED")
+
+	cmd := NewShellCommandAction(&lang.ShellCmd{
+		Command: `echo "$MATRYOSHKA_INHERITED $MATRYOSHKA_FOO"`,
+		Shell:   "sh",
+		Env:     map[string]string{"MATRYOSHKA_FOO": "foo"},
+	})
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("command failed: %s", err)
+	}
+
+	want := "inherited foo\n"
+	if string(cmd.Output()) != want {
+		t.Errorf("wanted output %q; got %q", want, cmd.Output())
+	}
+}
+
+func TestShellCommandAction_Run_ClearEnv(t *testing.T) {
+	os.Setenv("MATRYOSHKA_INHERITED", "inherited")
+	defer os.Unsetenv("MATRYOSHKA_INHERITED")
+
+	cmd := NewShellCommandAction(&lang.ShellCmd{
+		Command:  `echo "$MATRYOSHKA_INHERITED$MATRYOSHKA_FOO"`,
+		Shell:    "/bin/sh",
+		Env:      map[string]string{"MATRYOSHKA_FOO": "foo"},
+		ClearEnv: true,
+	})
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("command failed: %s", err)
+	}
+
+	want := "foo\n"
+	if string(cmd.Output()) != want {
+		t.Errorf("wanted output %q; got %q", want, cmd.Output())
+	}
+}
+
+func TestShellCommandAction_Run_Cwd(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// resolve any symlinks in the path of the temporary directory, as the
+	// shell reports the physical path
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewShellCommandAction(&lang.ShellCmd{Command: "pwd -P", Shell: "sh", Cwd: dir})
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("command failed: %s", err)
+	}
+
+	if want := dir + "\n"; string(cmd.Output()) != want {
+		t.Errorf("wanted output %q; got %q", want, cmd.Output())
+	}
+}
+
+func TestShellCommandAction_Run_Argv(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// the argument is passed as is, without being interpreted by a shell
+	path := filepath.Join(dir, "a file; with $pecial chars")
+	cmd := NewShellCommandAction(&lang.ShellCmd{
+		Command: "touch '...'",
+		Argv:    []string{"touch", path},
+	})
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("command failed: %s", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("wanted file to be created; got %s", err)
+	}
+
+	if want := "[exec]: touch '...'"; cmd.String() != want {
+		t.Errorf("wanted %s; got %s", want, cmd)
+	}
+}
+
 func TestShellCommandAction_String(t *testing.T) {
 	command := "foo bar"
 	cmd := newCommand(command)
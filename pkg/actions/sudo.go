@@ -0,0 +1,122 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// sudoGrace is the length of time a command run via sudo is given to exit
+	// after being asked to terminate, before it is killed
+	sudoGrace = 5 * time.Second
+)
+
+var (
+	// sudoPath is the sudo binary
+	sudoPath = "sudo"
+
+	// isRoot returns true if the current process is running as root, in which
+	// case commands are run directly rather than via sudo
+	isRoot = func() bool { return os.Geteuid() == 0 }
+
+	// isInteractive returns true if stdin is a terminal, in which case the
+	// user may be prompted for a password
+	isInteractive = func() bool {
+		info, err := os.Stdin.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+)
+
+// ErrSudoNonInteractive is returned when sudo credentials are required, but
+// cannot be prompted for.
+var ErrSudoNonInteractive = errors.New("sudo credentials are required, but cannot be prompted for when not running interactively; run 'sudo -v' first, or run interactively")
+
+// privileged returns true if the given value must run as root via sudo, i.e.
+// it has a Privileged method that returns true.
+func privileged(v interface{}) bool {
+	p, ok := v.(interface{ Privileged() bool })
+	return ok && p.Privileged()
+}
+
+// sudoArgs returns the arguments to sudo for running the given command as
+// root. Sudo never prompts for a password, as credentials are acquired
+// up front with AcquireSudo.
+func sudoArgs(command ...string) []string {
+	return append([]string{"-n", "--"}, command...)
+}
+
+// AcquireSudo ensures that sudo credentials are cached, such that commands can
+// be run via sudo without prompting for a password. If the credentials are not
+// already cached, the user is prompted for a password when interactive is
+// true. Otherwise ErrSudoNonInteractive is returned. Nothing is done when
+// running as root.
+func AcquireSudo(ctx context.Context, interactive bool) error {
+	if isRoot() {
+		return nil
+	}
+
+	if _, err := exec.LookPath(sudoPath); err != nil {
+		return fmt.Errorf("sudo: %w", err)
+	}
+
+	// the credentials may already be cached, or may not be required at all
+	if err := exec.CommandContext(ctx, sudoPath, "-n", "-v").Run(); err == nil {
+		return nil
+	}
+
+	if !interactive {
+		return ErrSudoNonInteractive
+	}
+
+	cmd := exec.CommandContext(ctx, sudoPath, "-v")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sudo: could not acquire credentials: %w", err)
+	}
+
+	return nil
+}
+
+// AcquireSudoFor acquires sudo credentials, as for AcquireSudo, on behalf of
+// the deps with the given names, which require them. Nothing is done if no
+// names are given. The user is only prompted for a password when stdin is a
+// terminal. The error returned, if any, names the deps.
+func AcquireSudoFor(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	if err := AcquireSudo(ctx, isInteractive()); err != nil {
+		return fmt.Errorf("sudo is required by %s: %w", strings.Join(names, ", "), err)
+	}
+	return nil
+}
+
+// KeepSudoAlive refreshes the cached sudo credentials at the given interval,
+// until the given context is done, such that the credentials acquired by
+// AcquireSudo do not expire during a long run.
+func KeepSudoAlive(ctx context.Context, interval time.Duration) {
+	if isRoot() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a failure is surfaced by the next command run via sudo
+			_ = exec.CommandContext(ctx, sudoPath, "-n", "-v").Run()
+		}
+	}
+}
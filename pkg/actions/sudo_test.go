@@ -0,0 +1,196 @@
+//go:build !windows
+// +build !windows
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+// fakeSudo replaces sudo with a script that records its arguments in the given
+// directory, and then runs the command without escalating privileges. The
+// script fails to validate credentials when valid is false. The returned
+// function restores sudo.
+func fakeSudo(t *testing.T, dir string, valid bool) func() {
+	exit := "0"
+	if !valid {
+		exit = "1"
+	}
+
+	script := `#!/bin/sh
+printf '%s\n' "$*" >> ` + filepath.Join(dir, "args") + `
+if [ "$2" = "-v" ]; then exit ` + exit + `; fi
+shift 2
+exec "$@"
+`
+	path := filepath.Join(dir, "sudo")
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldPath, oldIsRoot := sudoPath, isRoot
+	sudoPath, isRoot = path, func() bool { return false }
+	return func() {
+		sudoPath, isRoot = oldPath, oldIsRoot
+	}
+}
+
+// sudoArgsRecorded returns the arguments sudo was called with, one call per
+// line.
+func sudoArgsRecorded(t *testing.T, dir string) string {
+	args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(args)
+}
+
+func TestShellCommandAction_Run_Sudo(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	defer fakeSudo(t, dir, true)()
+
+	cmd := NewShellCommandAction(&lang.ShellCmd{
+		Command: `echo "$FOO"`,
+		Shell:   "sh",
+		Env:     map[string]string{"FOO": "bar"},
+		Sudo:    true,
+	})
+	if !cmd.Privileged() {
+		t.Fatal("wanted command to be privileged")
+	}
+
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("command failed: %s", err)
+	}
+
+	if string(cmd.Output()) != "bar\n" {
+		t.Errorf("wanted output 'bar'; got %q", cmd.Output())
+	}
+
+	want := "-n -- env FOO=bar sh -c echo \"$FOO\"\n"
+	if got := sudoArgsRecorded(t, dir); got != want {
+		t.Errorf("wanted sudo args %q; got %q", want, got)
+	}
+}
+
+func TestShellCommandAction_Privileged_Root(t *testing.T) {
+	oldIsRoot := isRoot
+	isRoot = func() bool { return true }
+	defer func() { isRoot = oldIsRoot }()
+
+	cmd := NewShellCommandAction(&lang.ShellCmd{Command: "true", Shell: "sh", Sudo: true})
+	if cmd.Privileged() {
+		t.Error("did not expect command run as root to be privileged")
+	}
+}
+
+func TestAcquireSudo(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	defer fakeSudo(t, dir, true)()
+
+	// cached credentials are used, without prompting
+	if err := AcquireSudo(context.Background(), false); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	if got := sudoArgsRecorded(t, dir); got != "-n -v\n" {
+		t.Errorf("wanted sudo to validate cached credentials; got %q", got)
+	}
+}
+
+func TestAcquireSudo_NonInteractive(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	defer fakeSudo(t, dir, false)()
+
+	err := AcquireSudo(context.Background(), false)
+	if !errors.Is(err, ErrSudoNonInteractive) {
+		t.Errorf("wanted ErrSudoNonInteractive; got %v", err)
+	}
+}
+
+func TestAcquireSudoFor(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	defer fakeSudo(t, dir, false)()
+
+	oldIsInteractive := isInteractive
+	isInteractive = func() bool { return false }
+	defer func() { isInteractive = oldIsInteractive }()
+
+	// sudo is not run when no deps require it
+	if err := AcquireSudoFor(context.Background(), nil); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	err := AcquireSudoFor(context.Background(), []string{"foo", "bar"})
+	if !errors.Is(err, ErrSudoNonInteractive) {
+		t.Errorf("wanted ErrSudoNonInteractive; got %v", err)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "sudo is required by foo, bar: ") {
+		t.Errorf("wanted error naming the deps; got %v", err)
+	}
+	if got := sudoArgsRecorded(t, dir); got != "-n -v\n" {
+		t.Errorf("wanted sudo to be run once; got %q", got)
+	}
+}
+
+func TestCommandManager_Privileged(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	defer fakeSudo(t, dir, true)()
+
+	manager := &commandManager{install: []string{"true"}, privileged: true}
+	if err := manager.Install(context.Background(), "foo"); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	if got := sudoArgsRecorded(t, dir); !strings.HasPrefix(got, "-n -- true foo") {
+		t.Errorf("wanted install via sudo; got %q", got)
+	}
+
+	r := NewPackageResource(&lang.Package{Name: "foo"}, manager)
+	if !NewConvergeAction(r).(Privileged).Privileged() {
+		t.Error("wanted converging the package to be privileged")
+	}
+	if _, ok := NewCheckAction(r).(Privileged); ok {
+		t.Error("did not expect checking the package to be privileged")
+	}
+}
+
+func TestGitResource_WithSudo(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	repo := newTestRepo(t, dir)
+	defer fakeSudo(t, dir, true)()
+
+	dest := filepath.Join(dir, "src", "clone")
+	r := NewGitResource(&lang.Git{URL: repo.url, Dest: dest}).WithEnv(map[string]string{"FOO": "bar"}).WithSudo()
+	if !r.Privileged() {
+		t.Fatal("wanted git resource to be privileged")
+	}
+
+	ctx := context.Background()
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	// only the clone is run via sudo, with the environment passed explicitly
+	want := "-n -- env GIT_TERMINAL_PROMPT=0 FOO=bar git clone -- " + repo.url + " " + dest + "\n"
+	if got := sudoArgsRecorded(t, dir); got != want {
+		t.Errorf("wanted sudo args %q; got %q", want, got)
+	}
+}
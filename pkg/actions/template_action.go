@@ -0,0 +1,136 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+// TemplateResource is a Resource for a file rendered from a Go text/template.
+// The resource is in its desired state when the content of the destination
+// file matches the rendered template.
+type TemplateResource struct {
+
+	// src is the path to the template
+	src string
+
+	// dst is the path of the rendered file
+	dst string
+
+	// vars are the variables available to the template
+	vars map[string]interface{}
+}
+
+// NewTemplateResource constructs and returns a new TemplateResource from the
+// given template.
+func NewTemplateResource(t *lang.Template) *TemplateResource {
+	return &TemplateResource{
+		src:  t.Src,
+		dst:  t.Dst,
+		vars: t.Vars,
+	}
+}
+
+// Check returns nil if the destination file matches the rendered template.
+func (r *TemplateResource) Check(ctx context.Context) error {
+	rendered, dst, err := r.render()
+	if err != nil {
+		return err
+	}
+
+	current, err := ioutil.ReadFile(dst)
+	if err != nil {
+		return fmt.Errorf("template_action: %w", err)
+	}
+
+	if !bytes.Equal(current, rendered) {
+		return fmt.Errorf("template_action: %s does not match the rendered template", dst)
+	}
+
+	return nil
+}
+
+// Converge writes the rendered template to the destination file. The mode of
+// an existing file is preserved.
+func (r *TemplateResource) Converge(ctx context.Context) error {
+	rendered, dst, err := r.render()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(dst)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(dst), defaultDirMode); err != nil {
+			return fmt.Errorf("template_action: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("template_action: %w", err)
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("template_action: %s exists and is not a regular file", dst)
+	}
+
+	if err := ioutil.WriteFile(dst, rendered, defaultFileMode); err != nil {
+		return fmt.Errorf("template_action: %w", err)
+	}
+
+	return nil
+}
+
+// Diff returns a unified diff between the destination file and the rendered
+// template.
+func (r *TemplateResource) Diff(ctx context.Context) ([]byte, error) {
+	rendered, dst, err := r.render()
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := diffFile(dst, rendered)
+	if err != nil {
+		return nil, fmt.Errorf("template_action: %w", err)
+	}
+	return diff, nil
+}
+
+// render renders the template, returning the rendered content along with the
+// expanded path of the destination file. Referencing a variable that was not
+// provided is an error.
+func (r *TemplateResource) render() ([]byte, string, error) {
+	src, err := expandPath(r.src)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dst, err := expandPath(r.dst)
+	if err != nil {
+		return nil, "", err
+	}
+
+	text, err := ioutil.ReadFile(src)
+	if err != nil {
+		return nil, "", fmt.Errorf("template_action: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(src)).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, "", fmt.Errorf("template_action: %w", err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, r.vars); err != nil {
+		return nil, "", fmt.Errorf("template_action: %w", err)
+	}
+
+	return b.Bytes(), dst, nil
+}
+
+// String prints a string representation of the TemplateResource.
+func (r TemplateResource) String() string {
+	return fmt.Sprintf("[template]: %s -> %s", r.src, r.dst)
+}
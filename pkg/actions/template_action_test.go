@@ -0,0 +1,112 @@
+package actions
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+func TestTemplateResource(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	src := filepath.Join(dir, "gitconfig.tmpl")
+	dst := filepath.Join(dir, "out", "gitconfig")
+	err := ioutil.WriteFile(src, []byte("name = {{ .name }}\n{{ range .editors }}editor = {{ . }}\n{{ end }}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewTemplateResource(&lang.Template{
+		Src: src,
+		Dst: dst,
+		Vars: map[string]interface{}{
+			"name":    "foo",
+			"editors": []interface{}{"vim", "emacs"},
+		},
+	})
+	ctx := context.Background()
+
+	if err := r.Check(ctx); err == nil {
+		t.Fatal("wanted check to fail for a missing file")
+	}
+
+	diff, err := r.Diff(ctx)
+	if err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if !strings.Contains(string(diff), "--- "+os.DevNull) || !strings.Contains(string(diff), "+name = foo\n") {
+		t.Errorf("wanted diff from %s; got %s", os.DevNull, diff)
+	}
+
+	if err := r.Converge(ctx); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("wanted check to pass after converging; got %s", err)
+	}
+
+	got, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name = foo\neditor = vim\neditor = emacs\n"
+	if string(got) != want {
+		t.Errorf("wanted rendered content %q; got %q", want, got)
+	}
+
+	diff, err = r.Diff(ctx)
+	if err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if diff != nil {
+		t.Errorf("wanted no diff after converging; got %s", diff)
+	}
+}
+
+func TestTemplateResource_MissingVar(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	src := filepath.Join(dir, "tmpl")
+	if err := ioutil.WriteFile(src, []byte("{{ .missing }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewTemplateResource(&lang.Template{Src: src, Dst: filepath.Join(dir, "out")})
+	if err := r.Converge(context.Background()); err == nil {
+		t.Error("wanted error for a missing variable")
+	}
+}
+
+func TestTemplateResource_PreservesMode(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	src := filepath.Join(dir, "tmpl")
+	dst := filepath.Join(dir, "out")
+	if err := ioutil.WriteFile(src, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dst, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewTemplateResource(&lang.Template{Src: src, Dst: dst})
+	if err := r.Converge(context.Background()); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("wanted mode 600; got %o", info.Mode().Perm())
+	}
+}
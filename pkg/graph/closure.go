@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Closure returns a digest of the definition of the given dep, along with the
+// definitions of the deps it requires, directly or transitively. The digest
+// changes if the definition of any of these deps changes, or if a requirement
+// is added or removed.
+func Closure(dep *Dependency) string {
+	return closure(dep, make(map[*Dependency]string))
+}
+
+// closure returns the Closure of the given dep, caching the Closure of each
+// dep visited in the given map. The graph is assumed to be acyclic.
+func closure(dep *Dependency, cache map[*Dependency]string) string {
+	if digest, ok := cache[dep]; ok {
+		return digest
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%s", len(dep.Definition), dep.Definition)
+	for _, d := range dep.Dependencies {
+		digest := closure(d, cache)
+		fmt.Fprintf(h, "%d:%s", len(digest), digest)
+	}
+
+	digest := "sha256:" + hex.EncodeToString(h.Sum(nil))
+	cache[dep] = digest
+	return digest
+}
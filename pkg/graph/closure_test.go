@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+func TestClosure(t *testing.T) {
+	newGraph := func() (foo, bar, baz *Dependency) {
+		baz = NewDependency("baz")
+		baz.Definition = "sha256:baz"
+		bar = NewDependency("bar")
+		bar.Definition = "sha256:bar"
+		bar.Dependencies = []*Dependency{baz}
+		foo = NewDependency("foo")
+		foo.Definition = "sha256:foo"
+		foo.Dependencies = []*Dependency{bar}
+		return foo, bar, baz
+	}
+
+	foo, bar, _ := newGraph()
+	want := Closure(foo)
+	if !strings.HasPrefix(want, "sha256:") {
+		t.Errorf("wanted a sha256 digest; got %s", want)
+	}
+	if Closure(bar) == want {
+		t.Errorf("wanted the closures of foo and bar to differ")
+	}
+
+	// the closure changes with the definition of a transitive requirement
+	foo, _, baz := newGraph()
+	baz.Definition = "sha256:changed"
+	if Closure(foo) == want {
+		t.Errorf("wanted the closure to change with the definition of baz")
+	}
+
+	// or if a requirement is removed
+	foo, bar, _ = newGraph()
+	bar.Dependencies = nil
+	if Closure(foo) == want {
+		t.Errorf("wanted the closure to change without baz")
+	}
+
+	foo, _, _ = newGraph()
+	if got := Closure(foo); got != want {
+		t.Errorf("wanted closure %s; got %s", want, got)
+	}
+}
+
+func TestClosure_TemplateSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "closure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the closure is computed afresh for each run, from the parsed deps
+	src := filepath.Join(dir, "foo.tmpl")
+	closure := func(content string) string {
+		if err := ioutil.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		foo := &lang.Dep{
+			Name:        "foo",
+			MetCommands: []lang.Command{&lang.Template{Src: src, Dst: filepath.Join(dir, "foo")}},
+			Enable:      true,
+		}
+		g := NewDependencyGraph()
+		if err := g.Construct([]*lang.Dep{foo}); err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		return Closure(g.Get("foo"))
+	}
+
+	want := closure("{{ .foo }}\n")
+	if got := closure("{{ .foo }}\n"); got != want {
+		t.Errorf("wanted closure %s for an unchanged template; got %s", want, got)
+	}
+	if got := closure("{{ .bar }}\n"); got == want {
+		t.Errorf("wanted the closure to change with the template")
+	}
+}
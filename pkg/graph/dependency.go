@@ -1,6 +1,11 @@
 package graph
 
 import (
+	"fmt"
+	"time"
+
+	"go.starlark.net/syntax"
+
 	"github.com/nicktrav/matryoshka/pkg/actions"
 )
 
@@ -12,22 +17,192 @@ const (
 	// The state of this dep has not yet been evaluated.
 	Unknown State = iota
 
-	// The dep has been evaluated and either the deps of this dep are
-	// unsatisfied, or at least one of the met actions returns with an error,
-	// or both
+	// The dep has been evaluated and at least one of the met actions returns
+	// with an error, but no attempt was made to satisfy the dep, e.g. when
+	// only checking the status of the dep.
 	Unsatisfied
 
 	// The dep has been evaluated and all the deps of this dep are satisfied
 	// and the met actions all return without error.
 	Satisfied
+
+	// An action run while evaluating the dep exceeded its timeout, or the
+	// deadline for the run as a whole.
+	TimedOut
+
+	// The run was cancelled before, or while, evaluating the dep.
+	Cancelled
+
+	// The meet actions of the dep were attempted, as permitted by its retry
+	// policy, but the dep is still unsatisfied.
+	Failed
+
+	// The dep was not evaluated, as at least one of its requirements is not
+	// satisfied. The requirements are recorded in BlockedBy.
+	Blocked
+
+	// The dep was not evaluated, as the run stopped attempting deps after a
+	// failure elsewhere in the graph.
+	Skipped
+
+	// The met actions of the dep returned with an error during a dry run, so
+	// the meet actions would have been run.
+	WouldMeet
+)
+
+// States are all of the States, in the order in which they are summarised.
+var States = []State{Satisfied, WouldMeet, Unsatisfied, Failed, TimedOut, Cancelled, Blocked, Skipped, Unknown}
+
+// String returns a short, human readable description of the State.
+func (s State) String() string {
+	switch s {
+	case Unknown:
+		return "unknown"
+	case Unsatisfied:
+		return "unsatisfied"
+	case Satisfied:
+		return "satisfied"
+	case TimedOut:
+		return "timed out"
+	case Cancelled:
+		return "cancelled"
+	case Failed:
+		return "failed"
+	case Blocked:
+		return "blocked"
+	case Skipped:
+		return "skipped"
+	case WouldMeet:
+		return "would meet"
+	default:
+		return fmt.Sprintf("state(%d)", int(s))
+	}
+}
+
+// MarshalText returns the State as text, allowing a State to be used when
+// encoding a report as JSON.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText sets the State from its text, as returned by MarshalText,
+// allowing a State to be read from a JSON document.
+func (s *State) UnmarshalText(text []byte) error {
+	for _, state := range States {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown state: %s", text)
+}
+
+// Phase identifies the reason for which an Action was run.
+type Phase string
+
+const (
+
+	// MetPhase is the phase in which met actions are run to determine whether
+	// the dep is satisfied.
+	MetPhase Phase = "met"
+
+	// MeetPhase is the phase in which meet actions are run to attempt to
+	// satisfy the dep.
+	MeetPhase Phase = "meet"
 )
 
+// RetryPolicy determines how many times, and how often, the meet actions of a
+// Dependency are attempted.
+type RetryPolicy struct {
+
+	// Attempts is the total number of attempts. Values less than one are
+	// treated as one.
+	Attempts int
+
+	// Backoff is the delay before the second attempt. The delay is doubled
+	// for each subsequent attempt.
+	Backoff time.Duration
+
+	// ExitCodes are the exit codes of failed actions that may be retried. If
+	// empty, failed actions are retried regardless of exit code.
+	ExitCodes []int
+}
+
+// Retryable returns true if an action that failed with the given exit code
+// may be retried.
+func (p RetryPolicy) Retryable(exitCode int) bool {
+	if len(p.ExitCodes) == 0 {
+		return true
+	}
+
+	for _, code := range p.ExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// ActionResult records the outcome of running an Action on a Dependency.
+type ActionResult struct {
+
+	// Phase is the phase in which the Action was run.
+	Phase Phase
+
+	// Attempt is the attempt at satisfying the Dependency during which the
+	// Action was run. Attempt is zero for the initial met actions.
+	Attempt int
+
+	// Action is a description of the Action that was run.
+	Action string
+
+	// ExitCode is the exit code of the process run by the Action, or -1 if
+	// the Action failed without an exit code.
+	ExitCode int
+
+	// Err is the error returned by the Action, if any.
+	Err error
+
+	// Start is the time at which the Action started running.
+	Start time.Time
+
+	// Duration is the length of time the Action took to run.
+	Duration time.Duration
+
+	// Output is the output captured while running the Action, if the Action
+	// records its output. For a preview, Output describes the changes the
+	// Action would have made.
+	Output []byte
+
+	// Preview is true if the Action was not run, but instead previewed the
+	// changes it would make, as is done for the meet actions in a dry run.
+	Preview bool
+}
+
 // A dependency represents a node in the dependency graph.
 type Dependency struct {
 
 	// Name is the name of the dependency.
 	Name string
 
+	// Description is a human readable description of the dependency.
+	Description string
+
+	// Tags are the tags used to select the dependency.
+	Tags []string
+
+	// Pos is the position of the dep() call that declared the dependency.
+	// The filename of the position is the module containing the declaration.
+	Pos syntax.Position
+
+	// Definition is a digest of the definition of the dependency, which
+	// changes if the dep() call declaring it changes.
+	Definition string
+
+	// Sources are the paths of the files read by the actions of the
+	// dependency when they are run, i.e. the sources of its templates.
+	Sources []string
+
 	// Dependencies is the list of dependencies that must be satisfied before
 	// this dependency is satisfied.
 	Dependencies []*Dependency
@@ -39,10 +214,70 @@ type Dependency struct {
 	// MeetAction is the list of commands to run to attempt to satisfy the dependency.
 	MeetActions []actions.Action
 
-	// State is the cached state of the Dependency.
+	// Retry is the policy for retrying the meet actions.
+	Retry RetryPolicy
+
+	// Attempts is the number of times the meet actions were attempted.
+	Attempts int
+
+	// Cached is true if the dependency is satisfied without having been
+	// evaluated, as it was recently verified, and is unchanged since, or as
+	// it was verified when the plan being applied was made.
+	Cached bool
+
+	// Results is the outcome of each Action run on the dependency, in the
+	// order in which the Actions were run.
+	Results []*ActionResult
+
+	// Err is the reason the dependency is unsatisfied, if it was found to be
+	// unsatisfied when evaluated.
+	Err error
+
+	// BlockedBy are the names of the requirements that were not satisfied,
+	// if the dependency is Blocked.
+	BlockedBy []string
+
+	// State is the cached state of the Dependency. The State is written by
+	// the visitor that evaluates the Dependency, and is only read by visitors
+	// of the Dependency's dependents once that evaluation has completed. A
+	// Walker must preserve this ordering.
 	State
 }
 
+// RootCause returns true if the State is the cause of a dep not being
+// satisfied, rather than a consequence of another dep not being satisfied.
+func (s State) RootCause() bool {
+	switch s {
+	case Unsatisfied, Failed, TimedOut, Cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Failure returns the result of the last Action run on the dependency that
+// returned an error, or nil if no Action failed.
+func (d *Dependency) Failure() *ActionResult {
+	for i := len(d.Results) - 1; i >= 0; i-- {
+		if d.Results[i].Err != nil {
+			return d.Results[i]
+		}
+	}
+	return nil
+}
+
+// Previews returns the results of the Actions previewed during the last
+// attempt at satisfying the dependency.
+func (d *Dependency) Previews() []*ActionResult {
+	var previews []*ActionResult
+	for _, result := range d.Results {
+		if result.Preview && result.Attempt == d.Attempts {
+			previews = append(previews, result)
+		}
+	}
+	return previews
+}
+
 // NewDependency returns a pointer to a new Dependency.
 func NewDependency(name string) *Dependency {
 	return &Dependency{
@@ -0,0 +1,70 @@
+package graph
+
+import "testing"
+
+func TestState_String(t *testing.T) {
+	want := map[State]string{
+		Unknown:     "unknown",
+		Unsatisfied: "unsatisfied",
+		Satisfied:   "satisfied",
+		TimedOut:    "timed out",
+		Cancelled:   "cancelled",
+		Failed:      "failed",
+		Blocked:     "blocked",
+		Skipped:     "skipped",
+		WouldMeet:   "would meet",
+		State(42):   "state(42)",
+	}
+
+	for state, s := range want {
+		if state.String() != s {
+			t.Errorf("wanted %s; got %s", s, state.String())
+		}
+	}
+}
+
+func TestState_UnmarshalText(t *testing.T) {
+	for _, want := range States {
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+
+		var got State
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		if got != want {
+			t.Errorf("wanted %s; got %s", want, got)
+		}
+	}
+
+	var s State
+	if err := s.UnmarshalText([]byte("bogus")); err == nil {
+		t.Errorf("wanted an error for an unknown state")
+	}
+}
+
+func TestDependency_Failure_NoFailure(t *testing.T) {
+	dep := NewDependency("foo")
+	dep.Results = []*ActionResult{{Phase: MetPhase}}
+
+	if dep.Failure() != nil {
+		t.Errorf("wanted no failure; got %+v", dep.Failure())
+	}
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	policy := RetryPolicy{}
+	if !policy.Retryable(1) || !policy.Retryable(-1) {
+		t.Error("wanted any exit code to be retryable")
+	}
+
+	some := RetryPolicy{ExitCodes: []int{75}}
+	if !some.Retryable(75) {
+		t.Error("wanted exit code 75 to be retryable")
+	}
+	if some.Retryable(1) {
+		t.Error("wanted exit code 1 not to be retryable")
+	}
+}
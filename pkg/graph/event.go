@@ -0,0 +1,263 @@
+package graph
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is something that happened to a Dependency during a walk of a
+// DependencyGraph. Events are emitted by the walk, via an Events, and are
+// given to each Subscriber of the Events. An Event is one of DepEntered,
+// DepStarted, ActionStarted, ActionOutput, ActionFinished, RetryScheduled,
+// StateChanged, DepFinished or DepExited.
+type Event interface {
+
+	// Dependency returns the Dependency to which the Event happened.
+	Dependency() *Dependency
+
+	// When returns the time at which the Event happened.
+	When() time.Time
+}
+
+// EventHeader is embedded in each Event, and records when, and to which
+// Dependency, the Event happened.
+type EventHeader struct {
+
+	// Time is the time at which the Event happened.
+	Time time.Time
+
+	// Dep is the Dependency to which the Event happened.
+	Dep *Dependency
+}
+
+// Dependency returns the Dependency to which the Event happened.
+func (h EventHeader) Dependency() *Dependency {
+	return h.Dep
+}
+
+// When returns the time at which the Event happened.
+func (h EventHeader) When() time.Time {
+	return h.Time
+}
+
+// DepEntered is emitted when the Walker enters a dep, before its requirements
+// are walked. As with the PreVisit hook, a dep is entered each time it is
+// reached by a depth-first walk, even if it has already been visited.
+type DepEntered struct {
+	EventHeader
+}
+
+// DepStarted is emitted once for each dep, immediately before it is visited,
+// once all of its requirements have been visited.
+type DepStarted struct {
+	EventHeader
+}
+
+// DepFinished is emitted once for each dep, once it has been visited. The
+// State of the dep is final.
+type DepFinished struct {
+	EventHeader
+}
+
+// DepExited is emitted when the Walker leaves a dep, and is paired with the
+// DepEntered emitted when the dep was entered.
+type DepExited struct {
+	EventHeader
+}
+
+// ActionStarted is emitted before an Action of a dep is run.
+type ActionStarted struct {
+	EventHeader
+
+	// Phase is the phase in which the Action is run.
+	Phase Phase
+
+	// Attempt is the attempt at satisfying the dep during which the Action
+	// is run, or zero for the initial met actions.
+	Attempt int
+
+	// Action is a description of the Action.
+	Action string
+}
+
+// ActionOutput is emitted for each line of output of a running Action, for
+// those Actions that can stream their output.
+type ActionOutput struct {
+	EventHeader
+
+	// Phase is the phase in which the Action is run.
+	Phase Phase
+
+	// Attempt is the attempt at satisfying the dep during which the Action
+	// is run, or zero for the initial met actions.
+	Attempt int
+
+	// Action is a description of the Action.
+	Action string
+
+	// Line is the line of output, without its trailing newline.
+	Line string
+}
+
+// ActionFinished is emitted once an Action of a dep has run, or has been
+// previewed, in which case the Preview of the Result is true.
+type ActionFinished struct {
+	EventHeader
+
+	// Result is the outcome of the Action, as recorded on the dep.
+	Result *ActionResult
+}
+
+// RetryScheduled is emitted when an attempt at satisfying a dep has failed,
+// and the dep will be attempted again once the backoff has elapsed.
+type RetryScheduled struct {
+	EventHeader
+
+	// Attempt is the attempt that will be made.
+	Attempt int
+
+	// Backoff is the delay before the attempt is made.
+	Backoff time.Duration
+
+	// Err is the reason the previous attempt failed.
+	Err error
+}
+
+// StateChanged is emitted when the State of a dep is changed by the visitor
+// evaluating it.
+type StateChanged struct {
+	EventHeader
+
+	// From is the State of the dep before the change.
+	From State
+
+	// To is the State of the dep after the change.
+	To State
+
+	// Err is the reason the dep is unsatisfied, if any.
+	Err error
+}
+
+// Subscriber handles the Events emitted during a walk.
+type Subscriber interface {
+
+	// Handle handles the given Event. Handle is never called concurrently
+	// by the same Events, and must not emit Events itself.
+	Handle(event Event)
+}
+
+// SubscriberFunc is a function that is a Subscriber.
+type SubscriberFunc func(event Event)
+
+// Handle calls the function with the given Event.
+func (f SubscriberFunc) Handle(event Event) {
+	f(event)
+}
+
+// Events delivers the Events emitted during a walk to its Subscribers. Events
+// are delivered synchronously, to each Subscriber in the order in which they
+// subscribed, and in the order in which they are emitted. Events may be
+// emitted concurrently, in which case delivery is serialized.
+//
+// A nil Events discards any Events emitted to it.
+type Events struct {
+
+	// mu serializes the delivery of Events, and guards subscribers
+	mu sync.Mutex
+
+	// subscribers are given each Event, in order
+	subscribers []Subscriber
+
+	// now returns the current time
+	now func() time.Time
+}
+
+// NewEvents returns a new Events, delivering to the given Subscribers.
+func NewEvents(subscribers ...Subscriber) *Events {
+	return &Events{
+		subscribers: subscribers,
+		now:         time.Now,
+	}
+}
+
+// Subscribe adds the given Subscriber, which is given each Event emitted from
+// then on.
+func (e *Events) Subscribe(subscriber Subscriber) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subscribers = append(e.subscribers, subscriber)
+}
+
+// Emit delivers the given Event to each Subscriber, returning once they have
+// all handled it.
+func (e *Events) Emit(event Event) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, subscriber := range e.subscribers {
+		subscriber.Handle(event)
+	}
+}
+
+// header returns the EventHeader of an Event happening now to the given dep.
+func (e *Events) header(dep *Dependency) EventHeader {
+	if e == nil || e.now == nil {
+		return EventHeader{Time: time.Now(), Dep: dep}
+	}
+	return EventHeader{Time: e.now(), Dep: dep}
+}
+
+// Visitor returns a DepVisitor that runs the given DepVisitors, in order, as
+// for a CompositeVisitor, and emits the walk-level Events of each dep: a
+// DepEntered and DepExited around the PreVisit and PostVisit hooks, and a
+// DepStarted and DepFinished around the visit itself. The returned DepVisitor
+// is given to a Walker in place of the DepVisitors.
+func (e *Events) Visitor(visitors ...DepVisitor) DepVisitor {
+	visitor := NewCompositeVisitor(visitors...)
+	if len(visitors) == 1 {
+		visitor = visitors[0]
+	}
+	return &eventVisitor{events: e, visitor: visitor}
+}
+
+// eventVisitor is a DepVisitor that emits the walk-level Events of each dep it
+// visits, around those of the DepVisitor it wraps.
+type eventVisitor struct {
+
+	// events is where the Events are emitted
+	events *Events
+
+	// visitor is the DepVisitor being wrapped
+	visitor DepVisitor
+}
+
+// Visit emits a DepStarted, visits the dep, and emits a DepFinished.
+func (v *eventVisitor) Visit(dep *Dependency) error {
+	v.events.Emit(DepStarted{v.events.header(dep)})
+	defer func() {
+		v.events.Emit(DepFinished{v.events.header(dep)})
+	}()
+
+	return v.visitor.Visit(dep)
+}
+
+// PreVisit runs the PreVisit hook, and emits a DepEntered. Nothing is emitted
+// for a dep that is not in the graph.
+func (v *eventVisitor) PreVisit(dep *Dependency) {
+	v.visitor.PreVisit(dep)
+	if dep != nil {
+		v.events.Emit(DepEntered{v.events.header(dep)})
+	}
+}
+
+// PostVisit runs the PostVisit hook, and emits a DepExited. Nothing is
+// emitted for a dep that is not in the graph.
+func (v *eventVisitor) PostVisit(dep *Dependency) {
+	v.visitor.PostVisit(dep)
+	if dep != nil {
+		v.events.Emit(DepExited{v.events.header(dep)})
+	}
+}
@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+)
+
+func TestEvents_Emit(t *testing.T) {
+	var got []string
+	record := func(name string) Subscriber {
+		return SubscriberFunc(func(event Event) {
+			got = append(got, fmt.Sprintf("%s %s", name, event.Dependency().Name))
+		})
+	}
+
+	events := NewEvents(record("first"))
+	events.Emit(DepStarted{EventHeader{Dep: NewDependency("foo")}})
+	events.Subscribe(record("second"))
+	events.Emit(DepFinished{EventHeader{Dep: NewDependency("bar")}})
+
+	// each event is delivered to the subscribers in the order in which they
+	// subscribed
+	want := []string{"first foo", "first bar", "second bar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+}
+
+func TestEvents_Header(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := NewEvents()
+	events.now = func() time.Time { return now }
+
+	foo := NewDependency("foo")
+	event := DepStarted{events.header(foo)}
+	if !event.When().Equal(now) || event.Dependency() != foo {
+		t.Errorf("wanted event for foo at %s; got %+v", now, event)
+	}
+}
+
+func TestEvents_Emit_Nil(t *testing.T) {
+	var events *Events
+	events.Emit(DepStarted{events.header(NewDependency("foo"))})
+}
+
+func TestEvents_Visitor(t *testing.T) {
+	bar := NewDependency("bar")
+	baz := NewDependency("baz")
+	baz.Dependencies = []*Dependency{bar}
+	foo := NewDependency("foo")
+	foo.Dependencies = []*Dependency{bar, baz}
+
+	var got []string
+	events := NewEvents(SubscriberFunc(func(event Event) {
+		got = append(got, describeEvent(event))
+	}))
+
+	if err := NewWalker(events.Visitor(NewExecutor())).Walk(graphOf(bar, baz, foo), "foo"); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	// bar is entered each time it is reached, but only started once
+	want := []string{
+		"entered foo",
+		"entered bar", "started bar", "finished bar", "exited bar",
+		"entered baz",
+		"entered bar", "exited bar",
+		"started baz", "finished baz", "exited baz",
+		"started foo", "finished foo", "exited foo",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+}
+
+func TestExecutor_WithEvents(t *testing.T) {
+	foo := NewDependency("foo")
+	foo.MetActions = []actions.Action{&failNTimesAction{n: 2, err: errors.New("oh noes")}}
+	foo.MeetActions = []actions.Action{&countingAction{}}
+	foo.Retry = RetryPolicy{Attempts: 2}
+
+	var got []string
+	events := NewEvents(SubscriberFunc(func(event Event) {
+		got = append(got, describeEvent(event))
+	}))
+
+	if err := NewExecutor(WithEvents(events)).Visit(foo); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := []string{
+		"met 0 started", "met 0 exited -1",
+		"meet 1 started", "meet 1 exited 0",
+		"met 1 started", "met 1 exited -1",
+		"retry 2 after 0s: met action *graph.failNTimesAction failed: oh noes",
+		"meet 2 started", "meet 2 exited 0",
+		"met 2 started", "met 2 exited 0",
+		"unknown -> satisfied",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+}
+
+func TestExecutor_WithEvents_Output(t *testing.T) {
+	foo := NewDependency("foo")
+	action := &streamingAction{lines: []string{"foo", "bar"}}
+	foo.MetActions = []actions.Action{action}
+
+	var lines []string
+	events := NewEvents(SubscriberFunc(func(event Event) {
+		if event, ok := event.(ActionOutput); ok {
+			lines = append(lines, fmt.Sprintf("%s %s", event.Phase, event.Line))
+		}
+	}))
+
+	if err := NewExecutor(WithEvents(events)).Visit(foo); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := []string{"met foo", "met bar"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("wanted %v; got %v", want, lines)
+	}
+
+	// the output is no longer streamed once the action has run
+	if action.stream != nil {
+		t.Error("wanted output no longer streamed")
+	}
+}
+
+func TestStatusChecker_WithEvents(t *testing.T) {
+	bar := NewDependency("bar")
+	bar.MetActions = []actions.Action{newFailingAction()}
+	foo := NewDependency("foo")
+	foo.Dependencies = []*Dependency{bar}
+
+	var got []string
+	events := NewEvents(SubscriberFunc(func(event Event) {
+		if event, ok := event.(StateChanged); ok {
+			got = append(got, fmt.Sprintf("%s: %s", event.Dep.Name, describeEvent(event)))
+		}
+	}))
+
+	checker := NewStatusChecker(WithEvents(events))
+	for _, dep := range []*Dependency{bar, foo} {
+		if err := checker.Visit(dep); err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+	}
+
+	want := []string{"bar: unknown -> unsatisfied", "foo: unknown -> blocked"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+}
+
+// describeEvent returns a short description of the given Event.
+func describeEvent(event Event) string {
+	switch event := event.(type) {
+	case DepEntered:
+		return "entered " + event.Dep.Name
+	case DepStarted:
+		return "started " + event.Dep.Name
+	case DepFinished:
+		return "finished " + event.Dep.Name
+	case DepExited:
+		return "exited " + event.Dep.Name
+	case ActionStarted:
+		return fmt.Sprintf("%s %d started", event.Phase, event.Attempt)
+	case ActionFinished:
+		return fmt.Sprintf("%s %d exited %d", event.Result.Phase, event.Result.Attempt, event.Result.ExitCode)
+	case RetryScheduled:
+		return fmt.Sprintf("retry %d after %s: %s", event.Attempt, event.Backoff, event.Err)
+	case StateChanged:
+		return fmt.Sprintf("%s -> %s", event.From, event.To)
+	default:
+		return fmt.Sprintf("%T", event)
+	}
+}
+
+// streamingAction is an Action that streams the given lines of output.
+type streamingAction struct {
+	lines  []string
+	stream func(line string)
+}
+
+func (a *streamingAction) Run(ctx context.Context) error {
+	for _, line := range a.lines {
+		if a.stream != nil {
+			a.stream(line)
+		}
+	}
+	return nil
+}
+
+func (a *streamingAction) StreamOutput(fn func(line string)) {
+	a.stream = fn
+}
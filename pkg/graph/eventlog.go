@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// eventLogTime is the layout of the time at the start of each line of an
+// event log.
+const eventLogTime = "2006-01-02T15:04:05.000Z07:00"
+
+// NewEventLog returns a Subscriber that writes a line to the given writer for
+// each Event of a walk, other than the entering and exiting of deps, which
+// only reflect the shape of the graph. Each line starts with the time of the
+// Event and the name of the dep, e.g.
+//
+//	2020-01-01T00:00:00.000Z foo: met action [sh]: true started
+//
+// Errors writing to the writer are ignored, as the log must not interrupt
+// the walk.
+func NewEventLog(w io.Writer) Subscriber {
+	return &eventLog{writer: w}
+}
+
+// eventLog is a Subscriber that writes a line for each Event.
+type eventLog struct {
+
+	// writer is the destination of the log
+	writer io.Writer
+}
+
+// Handle writes the line for the given Event, if any.
+func (l *eventLog) Handle(event Event) {
+	var message string
+	switch event := event.(type) {
+	case DepStarted:
+		message = "started"
+	case DepFinished:
+		message = fmt.Sprintf("finished %s", event.Dep.State)
+	case ActionStarted:
+		message = fmt.Sprintf("%s action %s started", event.Phase, event.Action)
+		if event.Attempt > 0 {
+			message += fmt.Sprintf(" (attempt %d)", event.Attempt)
+		}
+	case ActionOutput:
+		message = fmt.Sprintf("| %s", event.Line)
+	case ActionFinished:
+		result := event.Result
+		verb := "exited"
+		if result.Preview {
+			verb = "previewed"
+		}
+		message = fmt.Sprintf("%s action %s %s %d after %s", result.Phase, result.Action, verb, result.ExitCode, result.Duration.Round(time.Millisecond))
+		if result.Err != nil {
+			message += fmt.Sprintf(": %s", result.Err)
+		}
+	case RetryScheduled:
+		message = fmt.Sprintf("attempt %d in %s: %s", event.Attempt, event.Backoff, event.Err)
+	case StateChanged:
+		message = fmt.Sprintf("%s -> %s", event.From, event.To)
+		if event.Err != nil {
+			message += fmt.Sprintf(": %s", event.Err)
+		}
+	default:
+		return
+	}
+
+	_, _ = fmt.Fprintf(l.writer, "%s %s: %s\n", event.When().UTC().Format(eventLogTime), event.Dependency().Name, message)
+}
@@ -0,0 +1,45 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventLog_Handle(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := NewEventLog(buf)
+
+	foo := NewDependency("foo")
+	at := EventHeader{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Dep: foo}
+
+	log.Handle(DepEntered{at})
+	log.Handle(DepStarted{at})
+	log.Handle(ActionStarted{EventHeader: at, Phase: MeetPhase, Attempt: 1, Action: "[sh]: make"})
+	log.Handle(ActionOutput{EventHeader: at, Phase: MeetPhase, Attempt: 1, Action: "[sh]: make", Line: "oh noes"})
+	log.Handle(ActionFinished{EventHeader: at, Result: &ActionResult{
+		Phase:    MeetPhase,
+		Attempt:  1,
+		Action:   "[sh]: make",
+		ExitCode: 2,
+		Err:      errors.New("exit status 2"),
+		Duration: 1500 * time.Millisecond,
+	}})
+	log.Handle(RetryScheduled{EventHeader: at, Attempt: 2, Backoff: time.Second, Err: errors.New("meet failed")})
+	foo.State = Failed
+	log.Handle(StateChanged{EventHeader: at, From: Unknown, To: Failed, Err: errors.New("meet failed")})
+	log.Handle(DepFinished{at})
+	log.Handle(DepExited{at})
+
+	want := "2020-01-01T00:00:00.000Z foo: started\n" +
+		"2020-01-01T00:00:00.000Z foo: meet action [sh]: make started (attempt 1)\n" +
+		"2020-01-01T00:00:00.000Z foo: | oh noes\n" +
+		"2020-01-01T00:00:00.000Z foo: meet action [sh]: make exited 2 after 1.5s: exit status 2\n" +
+		"2020-01-01T00:00:00.000Z foo: attempt 2 in 1s: meet failed\n" +
+		"2020-01-01T00:00:00.000Z foo: unknown -> failed: meet failed\n" +
+		"2020-01-01T00:00:00.000Z foo: finished failed\n"
+	if buf.String() != want {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
@@ -1,7 +1,12 @@
 package graph
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/nicktrav/matryoshka/pkg/actions"
 )
@@ -14,13 +19,61 @@ var Debug = func(e *executor) {
 }
 
 // DryRun ensures that the "meet" actions are not run. "Met" actions are run.
+// Any "meet" actions that can preview their changes are previewed instead.
 var DryRun = func(e *executor) {
 	e.dryRun = true
 }
 
+// FailFast stops the executor from attempting any further deps once a dep has
+// failed, or timed out. Deps visited after the failure are Skipped. Without
+// FailFast, every dep that does not require a failed dep is still attempted.
+var FailFast = func(e *executor) {
+	e.failFast = true
+}
+
+// WithContext returns an ExecutorOption that runs all actions with the given
+// context. Once the context is done, any running action is stopped, and no
+// further actions are run.
+func WithContext(ctx context.Context) ExecutorOption {
+	return func(e *executor) {
+		e.ctx = ctx
+	}
+}
+
+// WithPlan returns an ExecutorOption that applies the given Plan. Deps planned
+// as already met are satisfied without running any action, and the met actions
+// of deps planned to be met are not run before their meet actions. Visiting a
+// dep that is not in the Plan is an error.
+func WithPlan(plan *Plan) ExecutorOption {
+	return func(e *executor) {
+		e.plan = plan
+	}
+}
+
+// WithVerified returns an ExecutorOption that treats each dep for which the
+// given function returns true as satisfied, without running any of its
+// actions, e.g. as the dep was recently verified and is unchanged since. Such
+// a dep is marked as Cached. A dep is only considered once its requirements
+// are satisfied.
+func WithVerified(verified func(dep *Dependency) bool) ExecutorOption {
+	return func(e *executor) {
+		e.verified = verified
+	}
+}
+
+// WithEvents returns an ExecutorOption that emits the action-level Events of
+// each dep to the given Events: as each action is started, produces output
+// and finishes, as each retry is scheduled, and as the State of the dep is
+// changed.
+func WithEvents(events *Events) ExecutorOption {
+	return func(e *executor) {
+		e.events = events
+	}
+}
+
 // NewExecutor returns a new executor, with the given options.
 func NewExecutor(options ...ExecutorOption) DepVisitor {
-	e := &executor{}
+	e := &executor{ctx: context.Background()}
 
 	for _, option := range options {
 		option(e)
@@ -38,12 +91,35 @@ type executor struct {
 
 	// DryRun determines whether the "meet" action on each dep will be run.
 	dryRun bool
+
+	// ctx is the context in which actions are run.
+	ctx context.Context
+
+	// events, if any, is where the action-level Events are emitted.
+	events *Events
+
+	// plan, if any, is the Plan being applied.
+	plan *Plan
+
+	// verified, if any, determines the deps that need not be evaluated.
+	verified func(dep *Dependency) bool
+
+	// failFast determines whether deps are skipped after a failure.
+	failFast bool
+
+	// mu guards failed, as deps may be visited concurrently.
+	mu sync.Mutex
+
+	// failed is true once a dep has failed, or timed out.
+	failed bool
 }
 
 // Visit attempts to satisfy the current dependency, first checking that dep
 // has not already been run, then checking the current deps deps to see if it
 // is eligible to be run, then checking the current met actions. If the dep is
-// still eligible to be run, the meet actions are run.
+// still eligible to be run, the meet actions are run, followed by the met
+// actions. If the dep is still not satisfied, the meet and met actions are
+// attempted again, as permitted by the dep's retry policy.
 //
 // This visitor assumes that a node is only visited once, hence the state of
 // the dep should be unknown at the time of visiting.
@@ -54,73 +130,192 @@ func (e *executor) Visit(dep *Dependency) error {
 		return fmt.Errorf("executor: dep %s already visited", dep.Name)
 	}
 
-	// check actions of the deps below us
-	for _, d := range dep.Dependencies {
+	// if any of the deps below us are not satisfied, we're blocked. This is
+	// checked first, such that a dep is attributed to the requirement that
+	// failed or was stopped, rather than to the run being stopped
+	if e.block(dep) {
+		return nil
+	}
+
+	// if the run has been stopped, there is no point going any further
+	if err := e.context().Err(); err != nil {
+		e.setState(dep, stoppedState(err), err)
+		return nil
+	}
+
+	// with fail-fast, no further independent deps are attempted after a
+	// failure
+	if e.failFast && e.hasFailed() {
+		e.setState(dep, Skipped, errors.New("skipped after an earlier failure"))
+		return nil
+	}
+
+	// a dep that was recently verified need not be evaluated again
+	if e.verified != nil && e.verified(dep) {
+		dep.Cached = true
+		e.setState(dep, Satisfied, nil)
+		return nil
+	}
 
-		// if any are unsatisfied, we're also unsatisfied
-		if d.State == Unsatisfied {
-			dep.State = Unsatisfied
+	// with a plan, only what was planned for this node is run
+	var step *PlanStep
+	if e.plan != nil {
+		if step = e.plan.Step(dep.Name); step == nil {
+			return fmt.Errorf("executor: dep %s is not in the plan", dep.Name)
+		}
+		if step.Action == PlanNone {
+			// the dep was verified when planned, not by this run
+			dep.Cached = true
+			e.setState(dep, Satisfied, nil)
 			return nil
 		}
 	}
 
-	// else check the met actions of this node
-	satisfied := true
-	for _, a := range dep.MetActions {
-		if e.debug {
-			enableDebug(a)
+	// else check the met actions of this node, unless it was planned to be
+	// met, in which case they were checked when planning
+	if step == nil || step.Action != PlanMeet {
+		err := e.runActions(dep, MetPhase, 0, dep.MetActions)
+		if err == nil {
+			// if our met actions were all satisfied, this dep is satisfied
+			e.setState(dep, Satisfied, nil)
+			return nil
 		}
-
-		// if any of the met actions did not return successful, stop early
-		if err := a.Run(); err != nil {
-			satisfied = false
-			break
+		if isStopped(err) {
+			e.stop(dep, err)
+			return nil
 		}
 	}
 
-	// if our met actions were all satisfied, this dep is satisfied
-	if satisfied {
-		dep.State = Satisfied
-		return nil
-	}
+	// otherwise, we need to run the meet actions to attempt to enforce state,
+	// retrying as permitted by the dep's retry policy
+	backoff := dep.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		dep.Attempts = attempt
 
-	// otherwise, we need to run the meet actions to attempt to enforce state
-	for _, a := range dep.MeetActions {
+		err := e.attempt(dep, attempt)
+		if err == nil {
+			// we made it through all the actions, this node is now satisfied
+			e.setState(dep, Satisfied, nil)
+			return nil
+		}
+		if isStopped(err) {
+			e.stop(dep, err)
+			return nil
+		}
 
-		// if running in dry-run mode, don't run the action
+		// in dry-run mode, the meet actions were only previewed, so the
+		// dep would be met, rather than having failed
 		if e.dryRun {
-			continue
+			e.setState(dep, WouldMeet, err)
+			return nil
 		}
 
-		if e.debug {
-			enableDebug(a)
+		if attempt >= dep.Retry.Attempts || !dep.Retry.Retryable(actions.ExitCode(err)) {
+			e.fail(dep, err)
+			return nil
 		}
 
-		// if any meet action could not be run, we're unsatisfied
-		if err := a.Run(); err != nil {
-			dep.State = Unsatisfied
+		// wait before the next attempt, unless the run is stopped
+		e.events.Emit(RetryScheduled{
+			EventHeader: e.events.header(dep),
+			Attempt:     attempt + 1,
+			Backoff:     backoff,
+			Err:         err,
+		})
+		if err := e.wait(backoff); err != nil {
+			e.stop(dep, err)
 			return nil
 		}
+		backoff *= 2
+	}
+}
+
+// attempt runs the meet actions of the given dep, followed by the met actions
+// to check whether the meet actions satisfied the dep. The error from the
+// first action that fails, if any, is returned.
+func (e *executor) attempt(dep *Dependency, attempt int) error {
+	// if running in dry-run mode, don't run the meet actions, but preview
+	// what they would do
+	if e.dryRun {
+		e.previewActions(dep, attempt, dep.MeetActions)
+	} else {
+		if err := e.runActions(dep, MeetPhase, attempt, dep.MeetActions); err != nil {
+			return err
+		}
 	}
 
 	// check the met actions again
-	for _, a := range dep.MetActions {
+	return e.runActions(dep, MetPhase, attempt, dep.MetActions)
+}
+
+// runActions runs the given actions in order, stopping at the first that
+// returns an error. The error is returned, describing the action that failed.
+func (e *executor) runActions(dep *Dependency, phase Phase, attempt int, as []actions.Action) error {
+	for _, a := range as {
 		if e.debug {
 			enableDebug(a)
 		}
 
-		// if any of the met actions did not return successful, we're unsatisfied
-		if err := a.Run(); err != nil {
-			dep.State = Unsatisfied
-			return nil
+		if err := e.runAction(dep, phase, attempt, a); err != nil {
+			if isStopped(err) {
+				return fmt.Errorf("%s action %s stopped: %w", phase, describe(a), err)
+			}
+			return fmt.Errorf("%s action %s failed: %w", phase, describe(a), err)
 		}
 	}
-
-	// we made it through all the deps, this node is now satisfied
-	dep.State = Satisfied
 	return nil
 }
 
+// previewActions records a preview of the changes each of the given actions
+// would make, for those actions that are Previewers. Actions that would make
+// no changes are not recorded.
+func (e *executor) previewActions(dep *Dependency, attempt int, as []actions.Action) {
+	for _, a := range as {
+		previewer, ok := a.(actions.Previewer)
+		if !ok {
+			continue
+		}
+
+		start := time.Now()
+		preview, err := previewer.Preview(e.context())
+		if err == nil && len(preview) == 0 {
+			continue
+		}
+
+		result := &ActionResult{
+			Phase:    MeetPhase,
+			Attempt:  attempt,
+			Action:   describe(a),
+			ExitCode: actions.ExitCode(err),
+			Err:      err,
+			Start:    start,
+			Duration: time.Since(start),
+			Output:   preview,
+			Preview:  true,
+		}
+		dep.Results = append(dep.Results, result)
+		e.events.Emit(ActionFinished{EventHeader: e.events.header(dep), Result: result})
+	}
+}
+
+// wait waits for the given length of time, returning early with the error
+// from the context if the context is done first.
+func (e *executor) wait(d time.Duration) error {
+	if d <= 0 {
+		return e.context().Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-e.context().Done():
+		return e.context().Err()
+	}
+}
+
 // PreVisit does nothing.
 func (e *executor) PreVisit(dep *Dependency) {
 }
@@ -129,6 +324,153 @@ func (e *executor) PreVisit(dep *Dependency) {
 func (e *executor) PostVisit(dep *Dependency) {
 }
 
+// runAction runs the given Action, recording the result on the Dependency.
+// The error returned by the Action, if any, is returned. The output of an
+// Action that can stream its output is emitted as it runs.
+func (e *executor) runAction(dep *Dependency, phase Phase, attempt int, action actions.Action) error {
+	description := describe(action)
+	e.events.Emit(ActionStarted{
+		EventHeader: e.events.header(dep),
+		Phase:       phase,
+		Attempt:     attempt,
+		Action:      description,
+	})
+
+	if streamer, ok := action.(actions.OutputStreamer); ok && e.events != nil {
+		streamer.StreamOutput(func(line string) {
+			e.events.Emit(ActionOutput{
+				EventHeader: e.events.header(dep),
+				Phase:       phase,
+				Attempt:     attempt,
+				Action:      description,
+				Line:        line,
+			})
+		})
+		defer streamer.StreamOutput(nil)
+	}
+
+	start := time.Now()
+	err := action.Run(e.context())
+
+	result := &ActionResult{
+		Phase:    phase,
+		Attempt:  attempt,
+		Action:   description,
+		ExitCode: actions.ExitCode(err),
+		Err:      err,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	if recorder, ok := action.(actions.OutputRecorder); ok {
+		result.Output = append([]byte{}, recorder.Output()...)
+	}
+	dep.Results = append(dep.Results, result)
+	e.events.Emit(ActionFinished{EventHeader: e.events.header(dep), Result: result})
+
+	return err
+}
+
+// context returns the context in which actions are run.
+func (e *executor) context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// stop marks the dep as stopped with the given error, which must wrap the
+// error from a context.
+func (e *executor) stop(dep *Dependency, err error) {
+	e.setState(dep, stoppedState(err), err)
+	if dep.State == TimedOut {
+		e.recordFailure()
+	}
+}
+
+// fail marks the dep as Failed with the given error, and records the failure
+// such that later deps are skipped, with FailFast.
+func (e *executor) fail(dep *Dependency, err error) {
+	e.setState(dep, Failed, err)
+	e.recordFailure()
+}
+
+// setState sets the State of the dep, along with the reason the dep is
+// unsatisfied, if any, and emits a StateChanged.
+func (e *executor) setState(dep *Dependency, state State, err error) {
+	from := dep.State
+	dep.State = state
+	dep.Err = err
+
+	e.events.Emit(StateChanged{
+		EventHeader: e.events.header(dep),
+		From:        from,
+		To:          state,
+		Err:         err,
+	})
+}
+
+// recordFailure records that a dep has failed.
+func (e *executor) recordFailure() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failed = true
+}
+
+// hasFailed returns true if a dep has failed.
+func (e *executor) hasFailed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.failed
+}
+
+// block marks the dep as Blocked if any of its requirements are not
+// satisfied, recording the names of those requirements, and returns true if
+// the dep is blocked.
+func (e *executor) block(dep *Dependency) bool {
+	var names, reasons []string
+	for _, d := range dep.Dependencies {
+		if d.State != Satisfied {
+			names = append(names, d.Name)
+			reasons = append(reasons, fmt.Sprintf("%s (%s)", d.Name, d.State))
+		}
+	}
+	if len(names) == 0 {
+		return false
+	}
+
+	noun := "requirement"
+	if len(names) > 1 {
+		noun = "requirements"
+	}
+
+	dep.BlockedBy = names
+	e.setState(dep, Blocked, fmt.Errorf("blocked by %s %s", noun, strings.Join(reasons, ", ")))
+	return true
+}
+
+// isStopped returns true if the given error was the result of an action being
+// stopped by its context, either by timing out or being cancelled.
+func isStopped(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// stoppedState returns the State of a dep whose evaluation was stopped with
+// the given error.
+func stoppedState(err error) State {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return TimedOut
+	}
+	return Cancelled
+}
+
+// describe returns a human readable description of the given Action.
+func describe(action actions.Action) string {
+	if stringer, ok := action.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", action)
+}
+
 // enableDebug enables debugging on the Action, if it is a Debugger.
 func enableDebug(action actions.Action) {
 	if debugger, ok := action.(actions.Debugger); ok {
@@ -1,8 +1,13 @@
 package graph
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/nicktrav/matryoshka/pkg/actions"
 )
@@ -38,8 +43,17 @@ func TestExecutor_Visit_DepsUnsatisfied(t *testing.T) {
 		t.Fatalf("wanted no error; got %+v", err)
 	}
 
-	if fooDep.State != Unsatisfied {
-		t.Fatalf("wanted state unsatisfied; got %+v", fooDep.State)
+	if fooDep.State != Blocked {
+		t.Fatalf("wanted state blocked; got %+v", fooDep.State)
+	}
+
+	if !reflect.DeepEqual(fooDep.BlockedBy, []string{"bar"}) {
+		t.Errorf("wanted blocked by [bar]; got %v", fooDep.BlockedBy)
+	}
+
+	want := "blocked by requirement bar (unsatisfied)"
+	if fooDep.Err == nil || fooDep.Err.Error() != want {
+		t.Errorf("wanted error '%s'; got %+v", want, fooDep.Err)
 	}
 }
 
@@ -102,8 +116,16 @@ func TestExecutor_Visit_DepsSatisfied_MetActionsUnsatisfied_MeetActionsFail(t *t
 		t.Fatalf("wanted no error; got %+v", err)
 	}
 
-	if fooDep.State != Unsatisfied {
-		t.Fatalf("wanted state unsatisfied ; got %+v", fooDep.State)
+	if fooDep.State != Failed {
+		t.Fatalf("wanted state failed; got %+v", fooDep.State)
+	}
+
+	if !errors.Is(fooDep.Err, meetAction.err) {
+		t.Errorf("wanted error to wrap meet action error; got %+v", fooDep.Err)
+	}
+
+	if fooDep.Failure() != fooDep.Results[1] {
+		t.Errorf("wanted failure to be the meet action; got %+v", fooDep.Failure())
 	}
 
 	if metAction.count != 1 {
@@ -138,8 +160,8 @@ func TestExecutor_Visit_DepsSatisfied_MetActionsUnsatisfied_MeetActionsSucceed_M
 		t.Fatalf("wanted no error; got %+v", err)
 	}
 
-	if fooDep.State != Unsatisfied {
-		t.Fatalf("wanted state unsatisfied ; got %+v", fooDep.State)
+	if fooDep.State != Failed {
+		t.Fatalf("wanted state failed; got %+v", fooDep.State)
 	}
 
 	if metAction.count != 2 {
@@ -190,6 +212,285 @@ func TestExecutor_Visit_DepsSatisfied_MetActionsUnsatisfied_MeetActionsSucceed_M
 	}
 }
 
+func TestExecutor_Visit_RecordsResults(t *testing.T) {
+	fooDep := NewDependency("foo")
+
+	metAction := &failNTimesAction{
+		err: errors.New("oh noes"),
+		n:   1, // fail once
+	}
+	fooDep.MetActions = []actions.Action{metAction}
+	fooDep.MeetActions = []actions.Action{&countingAction{}}
+
+	e := executor{}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	wantPhases := []Phase{MetPhase, MeetPhase, MetPhase}
+	if len(fooDep.Results) != len(wantPhases) {
+		t.Fatalf("wanted %d results; got %d", len(wantPhases), len(fooDep.Results))
+	}
+
+	for i, want := range wantPhases {
+		result := fooDep.Results[i]
+		if result.Phase != want {
+			t.Errorf("wanted result #%d to have phase %s; got %s", i, want, result.Phase)
+		}
+	}
+
+	if fooDep.Results[0].Err == nil || fooDep.Results[0].ExitCode != -1 {
+		t.Errorf("wanted first met action to fail; got %+v", fooDep.Results[0])
+	}
+
+	if fooDep.Results[2].Err != nil || fooDep.Results[2].ExitCode != 0 {
+		t.Errorf("wanted second met action to succeed; got %+v", fooDep.Results[2])
+	}
+
+	if fooDep.Results[1].Action != "*graph.countingAction" {
+		t.Errorf("wanted meet action described by its type; got %s", fooDep.Results[1].Action)
+	}
+}
+
+func TestExecutor_Visit_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fooDep := NewDependency("foo")
+	metAction := &countingAction{}
+	fooDep.MetActions = []actions.Action{metAction}
+
+	e := NewExecutor(WithContext(ctx))
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Cancelled {
+		t.Errorf("wanted state cancelled; got %s", fooDep.State)
+	}
+
+	if metAction.count != 0 {
+		t.Errorf("wanted met action not called; called %d times", metAction.count)
+	}
+}
+
+func TestExecutor_Visit_MeetActionTimedOut(t *testing.T) {
+	fooDep := NewDependency("foo")
+	fooDep.MetActions = []actions.Action{newFailingAction()}
+
+	meetAction := &failingAction{err: fmt.Errorf("shell_action: %w", context.DeadlineExceeded)}
+	fooDep.MeetActions = []actions.Action{meetAction, &countingAction{}}
+
+	e := executor{}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != TimedOut {
+		t.Errorf("wanted state timed out; got %s", fooDep.State)
+	}
+
+	if !errors.Is(fooDep.Err, context.DeadlineExceeded) {
+		t.Errorf("wanted error to wrap context.DeadlineExceeded; got %+v", fooDep.Err)
+	}
+
+	// no further actions are run once an action has timed out
+	if len(fooDep.Results) != 2 {
+		t.Errorf("wanted 2 actions run; got %d", len(fooDep.Results))
+	}
+}
+
+func TestExecutor_Visit_DepsTimedOut(t *testing.T) {
+	barDep := NewDependency("bar")
+	barDep.State = TimedOut
+
+	fooDep := NewDependency("foo")
+	fooDep.Dependencies = []*Dependency{barDep}
+
+	e := executor{}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Blocked {
+		t.Errorf("wanted state blocked; got %s", fooDep.State)
+	}
+}
+
+func TestExecutor_Visit_DepsTimedOut_RunStopped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	barDep := NewDependency("bar")
+	barDep.State = TimedOut
+
+	fooDep := NewDependency("foo")
+	fooDep.Dependencies = []*Dependency{barDep}
+
+	// a dep requiring the dep that timed out is blocked by it, rather than
+	// timing out itself
+	e := NewExecutor(WithContext(ctx))
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Blocked {
+		t.Errorf("wanted state blocked; got %s", fooDep.State)
+	}
+}
+
+func TestExecutor_Visit_Retry_SatisfiedOnSecondAttempt(t *testing.T) {
+	fooDep := NewDependency("foo")
+	fooDep.Retry = RetryPolicy{Attempts: 3}
+
+	metAction := &failNTimesAction{
+		err: errors.New("oh noes"),
+		n:   2, // fail the initial check, and the check after the first attempt
+	}
+	fooDep.MetActions = []actions.Action{metAction}
+
+	meetAction := &countingAction{}
+	fooDep.MeetActions = []actions.Action{meetAction}
+
+	e := executor{}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Satisfied {
+		t.Errorf("wanted state satisfied; got %s", fooDep.State)
+	}
+
+	if fooDep.Attempts != 2 {
+		t.Errorf("wanted 2 attempts; got %d", fooDep.Attempts)
+	}
+
+	if meetAction.count != 2 {
+		t.Errorf("wanted meet action called twice; called %d times", meetAction.count)
+	}
+
+	last := fooDep.Results[len(fooDep.Results)-1]
+	if last.Phase != MetPhase || last.Attempt != 2 {
+		t.Errorf("wanted last result to be the met action of attempt 2; got %+v", last)
+	}
+}
+
+func TestExecutor_Visit_Retry_AttemptsExhausted(t *testing.T) {
+	fooDep := NewDependency("foo")
+	fooDep.Retry = RetryPolicy{Attempts: 3}
+	fooDep.MetActions = []actions.Action{newFailingAction()}
+
+	meetAction := newFailingAction()
+	fooDep.MeetActions = []actions.Action{meetAction}
+
+	e := executor{}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Failed {
+		t.Errorf("wanted state failed; got %s", fooDep.State)
+	}
+
+	if fooDep.Attempts != 3 || meetAction.count != 3 {
+		t.Errorf("wanted 3 attempts; got %d attempts, %d meet calls", fooDep.Attempts, meetAction.count)
+	}
+}
+
+func TestExecutor_Visit_Retry_ExitCodeNotRetryable(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+
+	fooDep := NewDependency("foo")
+	fooDep.Retry = RetryPolicy{Attempts: 3, ExitCodes: []int{1, 2}}
+	fooDep.MetActions = []actions.Action{newFailingAction()}
+
+	meetAction := &failingAction{err: exitErr}
+	fooDep.MeetActions = []actions.Action{meetAction}
+
+	e := executor{}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Failed {
+		t.Errorf("wanted state failed; got %s", fooDep.State)
+	}
+
+	if meetAction.count != 1 {
+		t.Errorf("wanted meet action called once; called %d times", meetAction.count)
+	}
+}
+
+func TestExecutor_Visit_Retry_DryRun(t *testing.T) {
+	fooDep := NewDependency("foo")
+	fooDep.Retry = RetryPolicy{Attempts: 3}
+
+	metAction := newFailingAction()
+	fooDep.MetActions = []actions.Action{metAction}
+
+	e := executor{dryRun: true}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if metAction.count != 2 {
+		t.Errorf("wanted met action called twice; called %d times", metAction.count)
+	}
+}
+
+func TestExecutor_Visit_DryRun_PreviewsMeetActions(t *testing.T) {
+	fooDep := NewDependency("foo")
+
+	metAction := newFailingAction()
+	previewer := &previewAction{preview: []byte("+foo\n")}
+	unchanged := &previewAction{}
+	meetAction := &countingAction{}
+	fooDep.MetActions = []actions.Action{metAction}
+	fooDep.MeetActions = []actions.Action{previewer, unchanged, meetAction}
+
+	e := executor{dryRun: true}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != WouldMeet {
+		t.Errorf("wanted state would meet; got %s", fooDep.State)
+	}
+
+	// no meet action is run
+	if previewer.count != 0 || unchanged.count != 0 || meetAction.count != 0 {
+		t.Errorf("wanted no meet actions run; got %d, %d and %d", previewer.count, unchanged.count, meetAction.count)
+	}
+
+	// only the preview of the action that would make changes is recorded
+	previews := fooDep.Previews()
+	if len(previews) != 1 {
+		t.Fatalf("wanted 1 preview; got %d", len(previews))
+	}
+	if previews[0].Phase != MeetPhase || previews[0].Attempt != 1 || string(previews[0].Output) != "+foo\n" {
+		t.Errorf("wanted preview of meet action; got %+v", previews[0])
+	}
+}
+
+func TestExecutor_Visit_Retry_CancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fooDep := NewDependency("foo")
+	fooDep.Retry = RetryPolicy{Attempts: 3, Backoff: time.Hour}
+	fooDep.MetActions = []actions.Action{newFailingAction()}
+	fooDep.MeetActions = []actions.Action{newFailingAction()}
+
+	e := NewExecutor(WithContext(ctx))
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Cancelled {
+		t.Errorf("wanted state cancelled; got %s", fooDep.State)
+	}
+}
+
 func TestExecutor_EnableDebug_DebugAction(t *testing.T) {
 	a := debugAction{}
 
@@ -228,12 +529,139 @@ func TestNewExecutor_DryRunOption(t *testing.T) {
 	}
 }
 
+func TestExecutor_Visit_FailFast_SkipsAfterFailure(t *testing.T) {
+	failDep := NewDependency("fail")
+	failDep.MetActions = []actions.Action{newFailingAction()}
+
+	metAction := &countingAction{}
+	fooDep := NewDependency("foo")
+	fooDep.MetActions = []actions.Action{metAction}
+
+	e := NewExecutor(FailFast)
+	for _, dep := range []*Dependency{failDep, fooDep} {
+		if err := e.Visit(dep); err != nil {
+			t.Fatalf("wanted no error; got %+v", err)
+		}
+	}
+
+	if failDep.State != Failed {
+		t.Errorf("wanted state failed; got %s", failDep.State)
+	}
+
+	if fooDep.State != Skipped {
+		t.Errorf("wanted state skipped; got %s", fooDep.State)
+	}
+
+	if metAction.count != 0 {
+		t.Errorf("wanted met action not called; called %d times", metAction.count)
+	}
+}
+
+func TestExecutor_Visit_FailFast_BlocksDependents(t *testing.T) {
+	failDep := NewDependency("fail")
+	failDep.MetActions = []actions.Action{newFailingAction()}
+
+	fooDep := NewDependency("foo")
+	fooDep.Dependencies = []*Dependency{failDep}
+
+	e := NewExecutor(FailFast)
+	for _, dep := range []*Dependency{failDep, fooDep} {
+		if err := e.Visit(dep); err != nil {
+			t.Fatalf("wanted no error; got %+v", err)
+		}
+	}
+
+	// only deps independent of the failure are skipped
+	if fooDep.State != Blocked {
+		t.Errorf("wanted state blocked; got %s", fooDep.State)
+	}
+
+	if want := []string{"fail"}; !reflect.DeepEqual(fooDep.BlockedBy, want) {
+		t.Errorf("wanted blocked by %v; got %v", want, fooDep.BlockedBy)
+	}
+}
+
+func TestExecutor_Visit_KeepGoing_AttemptsAfterFailure(t *testing.T) {
+	failDep := NewDependency("fail")
+	failDep.MetActions = []actions.Action{newFailingAction()}
+
+	fooDep := NewDependency("foo")
+	fooDep.MetActions = []actions.Action{&countingAction{}}
+
+	e := NewExecutor()
+	for _, dep := range []*Dependency{failDep, fooDep} {
+		if err := e.Visit(dep); err != nil {
+			t.Fatalf("wanted no error; got %+v", err)
+		}
+	}
+
+	if fooDep.State != Satisfied {
+		t.Errorf("wanted state satisfied; got %s", fooDep.State)
+	}
+}
+
+func TestExecutor_Visit_BlockedByRequirements(t *testing.T) {
+	barDep := NewDependency("bar")
+	barDep.State = Failed
+	bazDep := NewDependency("baz")
+	bazDep.State = Satisfied
+	quxDep := NewDependency("qux")
+	quxDep.State = Skipped
+
+	fooDep := NewDependency("foo")
+	fooDep.Dependencies = []*Dependency{barDep, bazDep, quxDep}
+
+	e := executor{}
+	if err := e.Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if !reflect.DeepEqual(fooDep.BlockedBy, []string{"bar", "qux"}) {
+		t.Errorf("wanted blocked by [bar qux]; got %v", fooDep.BlockedBy)
+	}
+
+	want := "blocked by requirements bar (failed), qux (skipped)"
+	if fooDep.Err == nil || fooDep.Err.Error() != want {
+		t.Errorf("wanted error '%s'; got %+v", want, fooDep.Err)
+	}
+}
+
+func TestExecutor_Visit_WithVerified(t *testing.T) {
+	barMet := &countingAction{}
+	barDep := NewDependency("bar")
+	barDep.MetActions = []actions.Action{barMet}
+
+	fooMet := &countingAction{}
+	fooDep := NewDependency("foo")
+	fooDep.MetActions = []actions.Action{fooMet}
+
+	e := NewExecutor(WithVerified(func(dep *Dependency) bool {
+		return dep.Name == "foo"
+	}))
+	for _, dep := range []*Dependency{barDep, fooDep} {
+		if err := e.Visit(dep); err != nil {
+			t.Fatalf("wanted no error; got %+v", err)
+		}
+		if dep.State != Satisfied {
+			t.Errorf("wanted %s satisfied; got %s", dep.Name, dep.State)
+		}
+	}
+
+	if !fooDep.Cached || fooMet.count != 0 {
+		t.Errorf("wanted foo cached, without running its met action; cached %t, run %d times", fooDep.Cached, fooMet.count)
+	}
+
+	if barDep.Cached || barMet.count != 1 {
+		t.Errorf("wanted bar verified; cached %t, run %d times", barDep.Cached, barMet.count)
+	}
+}
+
 // countingAction is an Action that counts the number of time is was called.
 type countingAction struct {
 	count int
 }
 
-func (a *countingAction) Run() error {
+func (a *countingAction) Run(ctx context.Context) error {
 	a.count++
 	return nil
 }
@@ -249,7 +677,7 @@ func newFailingAction() *failingAction {
 	return &failingAction{err: errors.New("oh noes")}
 }
 
-func (a *failingAction) Run() error {
+func (a *failingAction) Run(ctx context.Context) error {
 	a.count++
 	return a.err
 }
@@ -262,7 +690,7 @@ type failNTimesAction struct {
 	err   error
 }
 
-func (a *failNTimesAction) Run() error {
+func (a *failNTimesAction) Run(ctx context.Context) error {
 	a.count++
 	if a.count > a.n {
 		return nil
@@ -270,11 +698,27 @@ func (a *failNTimesAction) Run() error {
 	return a.err
 }
 
+// previewAction is an Action that previews the given changes, while
+// recording the number of times it was run.
+type previewAction struct {
+	count   int
+	preview []byte
+}
+
+func (a *previewAction) Run(ctx context.Context) error {
+	a.count++
+	return nil
+}
+
+func (a *previewAction) Preview(ctx context.Context) ([]byte, error) {
+	return a.preview, nil
+}
+
 type debugAction struct {
 	debugCalled bool
 }
 
-func (a *debugAction) Run() error {
+func (a *debugAction) Run(ctx context.Context) error {
 	return nil
 }
 
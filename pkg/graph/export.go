@@ -0,0 +1,266 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is a format in which a DependencyGraph can be exported.
+type Format string
+
+const (
+
+	// DOT is the format read by Graphviz.
+	DOT Format = "dot"
+
+	// Mermaid is the format of a Mermaid flowchart, which can be embedded in
+	// Markdown documents.
+	Mermaid Format = "mermaid"
+
+	// JSON is a machine-readable list of the deps and their requirements.
+	JSON Format = "json"
+)
+
+// Formats are the supported export formats.
+var Formats = []Format{DOT, Mermaid, JSON}
+
+// ExportOption is an option for Export.
+type ExportOption func(*exporter)
+
+// WithState is an ExportOption that annotates each dep with its State, e.g.
+// once the met actions of the deps have been run.
+var WithState = func(e *exporter) {
+	e.state = true
+}
+
+// exporter writes a subgraph of a DependencyGraph in a given Format.
+type exporter struct {
+
+	// writer is the destination of the export
+	writer io.Writer
+
+	// state determines whether each dep is annotated with its State
+	state bool
+}
+
+// exportDep is a dep in the JSON export format.
+type exportDep struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Module      string   `json:"module,omitempty"`
+	Line        int32    `json:"line,omitempty"`
+	State       *State   `json:"state,omitempty"`
+	Requires    []string `json:"requires"`
+}
+
+// Export writes the subgraph of deps reachable from the given root to the
+// given writer, in the given Format. Each dep is annotated with its
+// description and the module in which it was declared.
+//
+// Deps are written in depth-first order from the root, with the requirements
+// of each dep in the order in which they were declared, such that the output
+// is stable for a given graph.
+func Export(w io.Writer, root *Dependency, format Format, options ...ExportOption) error {
+	if root == nil {
+		return fmt.Errorf("export: root dep not found")
+	}
+
+	e := &exporter{writer: w}
+	for _, option := range options {
+		option(e)
+	}
+
+	deps := reachable(root)
+	switch format {
+	case DOT:
+		return e.dot(deps)
+	case Mermaid:
+		return e.mermaid(deps)
+	case JSON:
+		return e.json(root, deps)
+	default:
+		return fmt.Errorf("export: unsupported format: %s", format)
+	}
+}
+
+// dot writes the given deps as a Graphviz digraph.
+func (e *exporter) dot(deps []*Dependency) error {
+	var b strings.Builder
+	b.WriteString("digraph deps {\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, dep := range deps {
+		attrs := fmt.Sprintf("label=%s", dotQuote(strings.Join(e.label(dep), "\n")))
+		if dep.Description != "" {
+			attrs += fmt.Sprintf(", tooltip=%s", dotQuote(dep.Description))
+		}
+		if e.state {
+			attrs += fmt.Sprintf(", style=filled, fillcolor=%s", dotQuote(stateColor(dep.State)))
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(dep.Name), attrs)
+	}
+
+	for _, dep := range deps {
+		for _, d := range dep.Dependencies {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(dep.Name), dotQuote(d.Name))
+		}
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(e.writer, b.String())
+	return err
+}
+
+// mermaid writes the given deps as a top-down Mermaid flowchart. Names may
+// contain characters that are not valid in a Mermaid node ID, so each node
+// is identified by its index.
+func (e *exporter) mermaid(deps []*Dependency) error {
+	ids := make(map[*Dependency]string)
+	for i, dep := range deps {
+		ids[dep] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+
+	for _, dep := range deps {
+		label := e.label(dep)
+		for i := range label {
+			label[i] = mermaidEscape(label[i])
+		}
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[dep], strings.Join(label, "<br/>"))
+	}
+
+	for _, dep := range deps {
+		for _, d := range dep.Dependencies {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[dep], ids[d])
+		}
+	}
+
+	if e.state {
+		for _, state := range States {
+			fmt.Fprintf(&b, "  classDef %s fill:%s\n", stateClass(state), stateColor(state))
+		}
+		for _, dep := range deps {
+			fmt.Fprintf(&b, "  class %s %s\n", ids[dep], stateClass(dep.State))
+		}
+	}
+
+	_, err := io.WriteString(e.writer, b.String())
+	return err
+}
+
+// json writes the given deps as a JSON document.
+func (e *exporter) json(root *Dependency, deps []*Dependency) error {
+	doc := struct {
+		Root string      `json:"root"`
+		Deps []exportDep `json:"deps"`
+	}{Root: root.Name}
+
+	for _, dep := range deps {
+		d := exportDep{
+			Name:        dep.Name,
+			Description: dep.Description,
+			Tags:        dep.Tags,
+			Requires:    []string{},
+		}
+		if dep.Pos.IsValid() {
+			d.Module, d.Line = dep.Pos.Filename(), dep.Pos.Line
+		}
+		if e.state {
+			state := dep.State
+			d.State = &state
+		}
+		for _, req := range dep.Dependencies {
+			d.Requires = append(d.Requires, req.Name)
+		}
+		doc.Deps = append(doc.Deps, d)
+	}
+
+	encoder := json.NewEncoder(e.writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// label returns the lines of the label of the given dep: its name, followed
+// by its description, source and state, where known.
+func (e *exporter) label(dep *Dependency) []string {
+	label := []string{dep.Name}
+	if dep.Description != "" {
+		label = append(label, dep.Description)
+	}
+	if dep.Pos.IsValid() {
+		label = append(label, fmt.Sprintf("%s:%d", dep.Pos.Filename(), dep.Pos.Line))
+	}
+	if e.state {
+		label = append(label, fmt.Sprintf("(%s)", dep.State))
+	}
+	return label
+}
+
+// reachable returns the deps reachable from the given root, in depth-first
+// pre-order.
+func reachable(root *Dependency) []*Dependency {
+	var deps []*Dependency
+	visited := make(map[*Dependency]bool)
+
+	var visit func(dep *Dependency)
+	visit = func(dep *Dependency) {
+		if visited[dep] {
+			return
+		}
+		visited[dep] = true
+		deps = append(deps, dep)
+
+		for _, d := range dep.Dependencies {
+			visit(d)
+		}
+	}
+	visit(root)
+
+	return deps
+}
+
+// stateColor returns the fill color of a node for a dep in the given State.
+func stateColor(state State) string {
+	switch state {
+	case Satisfied:
+		return "#c8e6c9"
+	case WouldMeet:
+		return "#fff9c4"
+	case Unsatisfied, Failed:
+		return "#ffcdd2"
+	case TimedOut, Cancelled, Blocked, Skipped:
+		return "#ffe0b2"
+	default:
+		return "#eeeeee"
+	}
+}
+
+// stateClass returns the name of the Mermaid class for a dep in the given
+// State.
+func stateClass(state State) string {
+	return strings.ReplaceAll(state.String(), " ", "_")
+}
+
+// dotQuote returns the given string as a quoted DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// mermaidEscape escapes the characters of the given string that have a
+// special meaning within a quoted Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"\n", " ",
+	).Replace(s)
+}
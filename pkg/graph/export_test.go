@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.starlark.net/syntax"
+)
+
+// exportGraph returns a small graph for testing exports, in which all
+// requires foo and bar, and foo requires bar.
+func exportGraph() *Dependency {
+	bar := NewDependency("bar")
+	bar.Description = `Installs "bar" <quickly>`
+	bar.State = Unsatisfied
+
+	foo := NewDependency("foo-linux")
+	foo.Pos = syntax.MakePosition(strPtr("deps/foo.dep"), 3, 7)
+	foo.Dependencies = []*Dependency{bar}
+	foo.State = Satisfied
+
+	all := NewDependency("all")
+	all.Pos = syntax.MakePosition(strPtr("deps/main.dep"), 1, 7)
+	all.Dependencies = []*Dependency{foo, bar}
+	all.State = Satisfied
+
+	return all
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestExport_DOT(t *testing.T) {
+	var b bytes.Buffer
+	if err := Export(&b, exportGraph(), DOT); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := `digraph deps {
+  node [shape=box];
+  "all" [label="all\ndeps/main.dep:1"];
+  "foo-linux" [label="foo-linux\ndeps/foo.dep:3"];
+  "bar" [label="bar\nInstalls \"bar\" <quickly>", tooltip="Installs \"bar\" <quickly>"];
+  "all" -> "foo-linux";
+  "all" -> "bar";
+  "foo-linux" -> "bar";
+}
+`
+	if b.String() != want {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestExport_DOT_WithState(t *testing.T) {
+	var b bytes.Buffer
+	if err := Export(&b, exportGraph(), DOT, WithState); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := `"bar" [label="bar\nInstalls \"bar\" <quickly>\n(unsatisfied)", tooltip="Installs \"bar\" <quickly>", style=filled, fillcolor="#ffcdd2"];`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("wanted output to contain %s; got:\n%s", want, b.String())
+	}
+}
+
+func TestExport_Mermaid(t *testing.T) {
+	var b bytes.Buffer
+	if err := Export(&b, exportGraph(), Mermaid, WithState); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := `graph TD
+  n0["all<br/>deps/main.dep:1<br/>(satisfied)"]
+  n1["foo-linux<br/>deps/foo.dep:3<br/>(satisfied)"]
+  n2["bar<br/>Installs #quot;bar#quot; #lt;quickly#gt;<br/>(unsatisfied)"]
+  n0 --> n1
+  n0 --> n2
+  n1 --> n2
+  classDef satisfied fill:#c8e6c9
+  classDef would_meet fill:#fff9c4
+  classDef unsatisfied fill:#ffcdd2
+  classDef failed fill:#ffcdd2
+  classDef timed_out fill:#ffe0b2
+  classDef cancelled fill:#ffe0b2
+  classDef blocked fill:#ffe0b2
+  classDef skipped fill:#ffe0b2
+  classDef unknown fill:#eeeeee
+  class n0 satisfied
+  class n1 satisfied
+  class n2 unsatisfied
+`
+	if b.String() != want {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestExport_JSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := Export(&b, exportGraph(), JSON); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	var doc struct {
+		Root string                   `json:"root"`
+		Deps []map[string]interface{} `json:"deps"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("could not decode export: %s", err)
+	}
+
+	if doc.Root != "all" {
+		t.Errorf("wanted root all; got %s", doc.Root)
+	}
+
+	want := []map[string]interface{}{
+		{"name": "all", "module": "deps/main.dep", "line": float64(1), "requires": []interface{}{"foo-linux", "bar"}},
+		{"name": "foo-linux", "module": "deps/foo.dep", "line": float64(3), "requires": []interface{}{"bar"}},
+		{"name": "bar", "description": `Installs "bar" <quickly>`, "requires": []interface{}{}},
+	}
+	if !reflect.DeepEqual(doc.Deps, want) {
+		t.Errorf("wanted deps %+v; got %+v", want, doc.Deps)
+	}
+
+	// state is only included when asked for
+	b.Reset()
+	if err := Export(&b, exportGraph(), JSON, WithState); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if !strings.Contains(b.String(), `"state": "unsatisfied"`) {
+		t.Errorf("wanted state in export; got:\n%s", b.String())
+	}
+}
+
+func TestExport_Invalid(t *testing.T) {
+	if err := Export(&bytes.Buffer{}, nil, DOT); err == nil {
+		t.Error("wanted error for missing root")
+	}
+	if err := Export(&bytes.Buffer{}, exportGraph(), Format("svg")); err == nil {
+		t.Error("wanted error for unsupported format")
+	}
+}
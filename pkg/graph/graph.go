@@ -11,6 +11,9 @@ type DependencyGraph struct {
 
 	// depMap is a mapping from dependency name to Dependency.
 	depMap map[string]*Dependency
+
+	// deps is the Dependencies in the order in which they were added.
+	deps []*Dependency
 }
 
 // NewDependencyGraph returns a pointer to a new DependencyGraph.
@@ -25,49 +28,89 @@ func NewDependencyGraph() *DependencyGraph {
 //
 // The graph is constructed by flattening the mappings of names to Values,
 // filtering only Dep types, constructing a new Dependency and placing it in
-// the dep map.
-func (g *DependencyGraph) Construct(deps []*lang.Dep) {
+// the dep map. An error is returned if the deps contain a cycle, or if two
+// distinct enabled deps share a name.
+//
+// Deps are resolved by name. Enabled deps sharing a name must be defined
+// identically, as checked by lang.ValidateDeps, such that any of them may be
+// used; the first found is added to the graph.
+func (g *DependencyGraph) Construct(deps []*lang.Dep) error {
 	for _, dep := range deps {
 		// exclude any deps that aren't enabled
 		if !dep.Enable {
 			continue
 		}
 
+		// the same dep may be bound to more than one variable
 		if _, ok := g.depMap[dep.Name]; ok {
-			// TODO(nickt) this implies there's a duplicate dep, and we should warn
 			continue
 		}
-		g.makeDep(dep)
+
+		if _, err := g.makeDep(dep, nil); err != nil {
+			return err
+		}
 	}
+
+	// a cycle can only be formed by deps sharing a name, so duplicates are
+	// checked for once any cycle has been reported
+	return lang.ValidateDeps(deps)
 }
 
 // makeDep translates a Dep into a new Dependency, using a cached value if a
 // Dependency with the same name is already present in the dep map.
-func (g *DependencyGraph) makeDep(rawDep *lang.Dep) *Dependency {
+//
+// The path contains the deps currently being translated that lead to the
+// given dep. Deps are resolved by name, so a cycle is detected when a dep
+// with the same name as one on the path is encountered.
+func (g *DependencyGraph) makeDep(rawDep *lang.Dep, path []*lang.Dep) (*Dependency, error) {
 	// deps that aren't enabled can still end up in the graph if referenced
 	// directly from the requirements block of another dep, and should not be
 	// added to the graph. Return nil as a sentinel value.
 	if !rawDep.Enable {
-		return nil
+		return nil, nil
 	}
 
 	// if dep is already in the map, return it
 	dep, found := g.depMap[rawDep.Name]
 	if found {
-		return dep
+		return dep, nil
+	}
+
+	// if the dep is already being translated, we've found a cycle
+	for i, d := range path {
+		if d.Name == rawDep.Name {
+			cycle := append([]*lang.Dep{}, path[i:]...)
+			return nil, depCycle(append(cycle, rawDep))
+		}
 	}
 
 	// else, construct the dependency
 	dep = &Dependency{
 		Name:        rawDep.Name,
-		MetActions:  convertCommands(rawDep.MetCommands),
-		MeetActions: convertCommands(rawDep.MeetCommands),
+		Description: rawDep.Description,
+		Tags:        rawDep.Tags,
+		Pos:         rawDep.Pos,
+		Definition:  rawDep.Definition(),
+		Sources:     lang.Sources(rawDep),
+		MetActions:  convertCommands(rawDep, rawDep.MetCommands, MetPhase),
+		MeetActions: convertCommands(rawDep, rawDep.MeetCommands, MeetPhase),
+	}
+	if rawDep.Retry != nil {
+		dep.Retry = RetryPolicy{
+			Attempts:  rawDep.Retry.Attempts,
+			Backoff:   rawDep.Retry.Backoff,
+			ExitCodes: rawDep.Retry.ExitCodes,
+		}
 	}
 
 	// for each requirement, recurse
+	path = append(path, rawDep)
 	var requirements []*Dependency
 	for _, req := range rawDep.Requirements {
-		reqDep := g.makeDep(req)
+		reqDep, err := g.makeDep(req, path)
+		if err != nil {
+			return nil, err
+		}
 		if reqDep != nil {
 			requirements = append(requirements, reqDep)
 		}
@@ -78,8 +121,24 @@ func (g *DependencyGraph) makeDep(rawDep *lang.Dep) *Dependency {
 
 	// and place this dep into the map
 	g.depMap[rawDep.Name] = dep
+	g.deps = append(g.deps, dep)
 
-	return dep
+	return dep, nil
+}
+
+// depCycle returns a CycleError for the given path of deps, where the last
+// dep in the path has the same name as the first. Each edge of the cycle is
+// declared by the dep() call of the requiring dep.
+func depCycle(path []*lang.Dep) *lang.CycleError {
+	var edges []lang.Edge
+	for i := 0; i < len(path)-1; i++ {
+		edges = append(edges, lang.Edge{
+			From: path[i].Name,
+			To:   path[i+1].Name,
+			Pos:  path[i].Pos,
+		})
+	}
+	return &lang.CycleError{Graph: "dep", Edges: edges}
 }
 
 // Get returns the Dependency with the given name from the graph.
@@ -91,22 +150,57 @@ func (g *DependencyGraph) Get(name string) *Dependency {
 	return dep
 }
 
-// Deps returns the a slice of all Dependencies in the DependencyGraph.
+// Deps returns the a slice of all Dependencies in the DependencyGraph, in the
+// order in which they were added by Construct. Each Dependency follows its
+// own dependencies.
 func (g *DependencyGraph) Deps() []*Dependency {
-	var deps []*Dependency
-	for _, val := range g.depMap {
-		deps = append(deps, val)
-	}
-	return deps
+	return append([]*Dependency{}, g.deps...)
 }
 
-// convertCommands takes a slice of pointers to ShellCmds and converts them
-// into a slice of Actions.
-func convertCommands(shellCommands []*lang.ShellCmd) []actions.Action {
+// convertCommands takes a slice of Commands and converts them into a slice of
+// Actions. Resources are converted into Actions that check the Resource in the
+// met phase, and converge the Resource in the meet phase. Shell commands
+// inherit the environment of the given dep, and run via sudo if the dep is
+// privileged.
+func convertCommands(dep *lang.Dep, commands []lang.Command, phase Phase) []actions.Action {
 	var as []actions.Action
-	for _, command := range shellCommands {
-		shell := actions.NewShellCommandAction(command)
-		as = append(as, shell)
+	for _, command := range commands {
+		var resource actions.Resource
+		switch c := command.(type) {
+		case *lang.ShellCmd:
+			cmd := c.WithEnv(dep.Env)
+			if dep.Privileged {
+				cmd = cmd.WithSudo()
+			}
+			as = append(as, actions.NewShellCommandAction(cmd))
+			continue
+		case *lang.File:
+			resource = actions.NewFileResource(c)
+		case *lang.Symlink:
+			resource = actions.NewSymlinkResource(c)
+		case *lang.Directory:
+			resource = actions.NewDirectoryResource(c)
+		case *lang.Absent:
+			resource = actions.NewAbsentResource(c)
+		case *lang.Template:
+			resource = actions.NewTemplateResource(c)
+		case *lang.Git:
+			git := actions.NewGitResource(c).WithEnv(dep.Env)
+			if dep.Privileged {
+				git = git.WithSudo()
+			}
+			resource = git
+		case *lang.Package:
+			resource = actions.NewPackageResource(c, actions.DetectPackageManager())
+		default:
+			continue
+		}
+
+		if phase == MetPhase {
+			as = append(as, actions.NewCheckAction(resource))
+		} else {
+			as = append(as, actions.NewConvergeAction(resource))
+		}
 	}
 	return as
 }
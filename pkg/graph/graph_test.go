@@ -1,8 +1,14 @@
 package graph
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 
+	"go.starlark.net/syntax"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
 	"github.com/nicktrav/matryoshka/pkg/lang"
 )
 
@@ -74,6 +80,156 @@ func TestDependencyGraph_Construct_DisabledRequirement(t *testing.T) {
 	}
 }
 
+func TestDependencyGraph_Construct_Cycle(t *testing.T) {
+	// deps are resolved by name, so a cycle is formed by a dep that requires,
+	// transitively, another dep with the same name
+	first := &lang.Dep{Name: "all", Enable: true}
+	baz := &lang.Dep{Name: "baz", Requirements: []*lang.Dep{first}, Enable: true}
+	foo := &lang.Dep{Name: "foo", Requirements: []*lang.Dep{baz}, Enable: true}
+	second := &lang.Dep{Name: "all", Requirements: []*lang.Dep{foo}, Enable: true}
+
+	g := NewDependencyGraph()
+	err := g.Construct([]*lang.Dep{second})
+	if err == nil {
+		t.Fatal("wanted error; got none")
+	}
+
+	cycleErr, ok := err.(*lang.CycleError)
+	if !ok {
+		t.Fatalf("wanted a CycleError; got %+v", err)
+	}
+
+	want := [][2]string{{"all", "foo"}, {"foo", "baz"}, {"baz", "all"}}
+	if len(cycleErr.Edges) != len(want) {
+		t.Fatalf("wanted %d edges; got %+v", len(want), cycleErr.Edges)
+	}
+	for i, edge := range cycleErr.Edges {
+		if edge.From != want[i][0] || edge.To != want[i][1] {
+			t.Errorf("wanted edge %s -> %s; got %s -> %s", want[i][0], want[i][1], edge.From, edge.To)
+		}
+	}
+}
+
+func TestDependencyGraph_Construct_Duplicate(t *testing.T) {
+	first := &lang.Dep{Name: "foo", Description: "first", Enable: true}
+	second := &lang.Dep{Name: "foo", Description: "second", Enable: true}
+	all := &lang.Dep{Name: "all", Requirements: []*lang.Dep{first, second}, Enable: true}
+
+	g := NewDependencyGraph()
+	err := g.Construct([]*lang.Dep{all})
+	if err == nil {
+		t.Fatal("wanted error; got none")
+	}
+
+	dupErr, ok := err.(*lang.DuplicateError)
+	if !ok {
+		t.Fatalf("wanted a DuplicateError; got %+v", err)
+	}
+	if dupErr.Name != "foo" {
+		t.Errorf("wanted duplicate foo; got %s", dupErr.Name)
+	}
+}
+
+func TestDependencyGraph_Construct_Metadata(t *testing.T) {
+	filename := "main.dep"
+	raw := &lang.Dep{
+		Name:        "foo",
+		Description: "Installs foo",
+		Tags:        []string{"editor"},
+		Enable:      true,
+		Pos:         syntax.MakePosition(&filename, 3, 7),
+	}
+
+	g := NewDependencyGraph()
+	if err := g.Construct([]*lang.Dep{raw}); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	dep := g.Get("foo")
+	if dep.Description != raw.Description {
+		t.Errorf("wanted description %q; got %q", raw.Description, dep.Description)
+	}
+	if !reflect.DeepEqual(dep.Tags, raw.Tags) {
+		t.Errorf("wanted tags %v; got %v", raw.Tags, dep.Tags)
+	}
+	if dep.Pos != raw.Pos {
+		t.Errorf("wanted position %s; got %s", raw.Pos, dep.Pos)
+	}
+}
+
+func TestDependencyGraph_Construct_Retry(t *testing.T) {
+	raw := &lang.Dep{
+		Name:   "foo",
+		Enable: true,
+		Retry:  &lang.Retry{Attempts: 4, Backoff: time.Second, ExitCodes: []int{1}},
+	}
+
+	g := NewDependencyGraph()
+	if err := g.Construct([]*lang.Dep{raw}); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	retry := g.Get("foo").Retry
+	if retry.Attempts != 4 || retry.Backoff != time.Second || len(retry.ExitCodes) != 1 {
+		t.Errorf("wanted retry policy from raw dep; got %+v", retry)
+	}
+}
+
+func TestConvertCommands_Resources(t *testing.T) {
+	commands := []lang.Command{
+		&lang.ShellCmd{Command: "true"},
+		&lang.File{Path: "/tmp/foo"},
+		&lang.Symlink{Src: "/tmp/foo", Dst: "/tmp/bar"},
+	}
+
+	met := convertCommands(&lang.Dep{}, commands, MetPhase)
+	meet := convertCommands(&lang.Dep{}, commands, MeetPhase)
+	if len(met) != 3 || len(meet) != 3 {
+		t.Fatalf("wanted 3 actions per phase; got %d and %d", len(met), len(meet))
+	}
+
+	want := []string{"[sh]: true", "[file]: /tmp/foo", "[symlink]: /tmp/bar -> /tmp/foo"}
+	for i := range commands {
+		if got := describe(met[i]); got != want[i] {
+			t.Errorf("wanted met action %s; got %s", want[i], got)
+		}
+		if got := describe(meet[i]); got != want[i] {
+			t.Errorf("wanted meet action %s; got %s", want[i], got)
+		}
+	}
+
+	// resources are checked in the met phase and converged in the meet phase
+	if reflect.TypeOf(met[1]) == reflect.TypeOf(meet[1]) {
+		t.Errorf("wanted different actions per phase; got %T for both", met[1])
+	}
+}
+
+func TestDependencyGraph_Construct_Env(t *testing.T) {
+	raw := &lang.Dep{
+		Name:   "foo",
+		Enable: true,
+		Env:    map[string]string{"FOO": "dep", "BAR": "dep"},
+		MetCommands: []lang.Command{
+			&lang.ShellCmd{Command: `echo "$FOO $BAR"`, Shell: "sh", Env: map[string]string{"BAR": "cmd"}},
+		},
+	}
+
+	g := NewDependencyGraph()
+	if err := g.Construct([]*lang.Dep{raw}); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	action := g.Get("foo").MetActions[0]
+	if err := action.Run(context.Background()); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	want := "dep cmd\n"
+	if got := string(action.(actions.OutputRecorder).Output()); got != want {
+		t.Errorf("wanted output %q; got %q", want, got)
+	}
+}
+
 func assertMapContainsDep(t *testing.T, g *DependencyGraph, depName string, wantedDep *Dependency) {
 	dep, found := g.depMap[depName]
 
@@ -88,7 +244,7 @@ func assertMapContainsDep(t *testing.T, g *DependencyGraph, depName string, want
 
 func TestDependencyGraph_Deps(t *testing.T) {
 	g := NewDependencyGraph()
-	g.depMap["foo"] = g.makeDep(fooRawDep)
+	g.depMap["foo"], _ = g.makeDep(fooRawDep, nil)
 
 	if len(g.Deps()) != 5 {
 		t.Fatalf("wanted deps have length 5; got %d", len(g.Deps()))
@@ -100,6 +256,25 @@ func TestDependencyGraph_Deps(t *testing.T) {
 	}
 }
 
+func TestDependencyGraph_Deps_Order(t *testing.T) {
+	g := NewDependencyGraph()
+	if err := g.Construct([]*lang.Dep{fooRawDep, excludedDep}); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	// each dep follows its requirements, in the order they were declared
+	want := []string{"baz", "boom", "bar", "bam", "foo"}
+
+	var got []string
+	for _, dep := range g.Deps() {
+		got = append(got, dep.Name)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted deps in order %v; got %v", want, got)
+	}
+}
+
 func TestDependencyGraph_Get_DepMissing(t *testing.T) {
 	g := NewDependencyGraph()
 
@@ -111,7 +286,7 @@ func TestDependencyGraph_Get_DepMissing(t *testing.T) {
 
 func TestDependencyGraph_Get_DepPresent(t *testing.T) {
 	g := NewDependencyGraph()
-	g.depMap[fooRawDep.Name] = g.makeDep(fooRawDep)
+	g.depMap[fooRawDep.Name], _ = g.makeDep(fooRawDep, nil)
 
 	dep := g.Get(fooRawDep.Name)
 	if dep == nil {
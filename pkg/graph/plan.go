@@ -0,0 +1,281 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PlanVersion is the version of the plan format written by Plan.WriteJSON.
+const PlanVersion = 1
+
+// PlanAction is what applying a Plan does for a single dep.
+type PlanAction string
+
+const (
+
+	// PlanNone is the action for a dep that is already met. Nothing is run.
+	PlanNone PlanAction = "none"
+
+	// PlanMeet is the action for a dep that is not met. Its meet actions
+	// are run, followed by its met actions.
+	PlanMeet PlanAction = "meet"
+
+	// PlanCheck is the action for a dep requiring a dep that is not met,
+	// such that whether the dep is met can only be determined once its
+	// requirements are met. Its met actions are run, followed by its meet
+	// actions if the dep is not met.
+	PlanCheck PlanAction = "check"
+)
+
+// Plan is a reviewable record of what applying a selection of the deps in a
+// DependencyGraph would do, which can later be applied exactly.
+type Plan struct {
+
+	// Version is the version of the plan format.
+	Version int `json:"version"`
+
+	// Created is the time at which the plan was made.
+	Created time.Time `json:"created"`
+
+	// Digest is the digest of the dep sources from which the plan was made,
+	// and of the templates the deps render.
+	Digest string `json:"digest"`
+
+	// Selection is the selection of deps from which the plan was made.
+	Selection Selection `json:"selection"`
+
+	// Steps are the steps of the plan, in the order in which they are run.
+	Steps []*PlanStep `json:"steps"`
+}
+
+// PlanStep is the step of a Plan for a single dep.
+type PlanStep struct {
+
+	// Name is the name of the dep.
+	Name string `json:"name"`
+
+	// Action is what is done for the dep.
+	Action PlanAction `json:"action"`
+
+	// Requires are the names of the requirements of the dep.
+	Requires []string `json:"requires,omitempty"`
+
+	// Meet describes the meet actions of the dep that would be run, if any,
+	// in the order in which they would be run.
+	Meet []string `json:"meet,omitempty"`
+
+	// Changes are the previews of the changes the meet actions would make,
+	// for those actions that can preview their changes.
+	Changes []*PlanChange `json:"changes,omitempty"`
+}
+
+// PlanChange is a preview of the change a meet action would make.
+type PlanChange struct {
+
+	// Action describes the meet action.
+	Action string `json:"action"`
+
+	// Diff is the preview of the change, if the preview succeeded.
+	Diff string `json:"diff,omitempty"`
+
+	// Error is the reason the change could not be previewed, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// NewPlan returns a Plan for the given roots, along with the requirements
+// reachable from them, once they have been walked by a planner. The steps are
+// in the order in which the deps are walked from the roots. An error is
+// returned if any dep was not planned, e.g. as planning was stopped.
+func NewPlan(selection Selection, digest string, roots ...*Dependency) (*Plan, error) {
+	deps, err := postOrder(roots...)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Version:   PlanVersion,
+		Created:   time.Now(),
+		Digest:    digest,
+		Selection: selection,
+		Steps:     []*PlanStep{},
+	}
+
+	for _, dep := range deps {
+		step := &PlanStep{Name: dep.Name}
+		pending := false
+		for _, d := range dep.Dependencies {
+			step.Requires = append(step.Requires, d.Name)
+			pending = pending || d.State == WouldMeet
+		}
+
+		switch {
+		case dep.State == Satisfied:
+			step.Action = PlanNone
+		case dep.State == WouldMeet && pending:
+			// the met actions were never run, as a requirement would be met
+			step.Action = PlanCheck
+		case dep.State == WouldMeet:
+			step.Action = PlanMeet
+			for _, a := range dep.MeetActions {
+				step.Meet = append(step.Meet, describe(a))
+			}
+			for _, preview := range dep.Previews() {
+				change := &PlanChange{Action: preview.Action, Diff: string(preview.Output)}
+				if preview.Err != nil {
+					change.Error = preview.Err.Error()
+				}
+				step.Changes = append(step.Changes, change)
+			}
+		default:
+			if dep.Err != nil {
+				return nil, fmt.Errorf("plan: dep %s is %s: %s", dep.Name, dep.State, dep.Err)
+			}
+			return nil, fmt.Errorf("plan: dep %s is %s", dep.Name, dep.State)
+		}
+
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	return plan, nil
+}
+
+// ReadPlan reads a Plan, as written by Plan.WriteJSON, from the given reader.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	plan := &Plan{}
+	if err := json.NewDecoder(r).Decode(plan); err != nil {
+		return nil, fmt.Errorf("plan: %s", err)
+	}
+
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("plan: unsupported version %d", plan.Version)
+	}
+
+	return plan, nil
+}
+
+// WriteJSON writes the Plan as a JSON document to the given writer.
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// WriteText writes a human readable description of the Plan to the given
+// writer, with a line for each step, followed by the meet actions and changes
+// of each dep that would be met.
+func (p *Plan) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, step := range p.Steps {
+		switch step.Action {
+		case PlanNone:
+			fmt.Fprintf(&b, "  %s: already met\n", step.Name)
+		case PlanMeet:
+			fmt.Fprintf(&b, "+ %s: meet\n", step.Name)
+			for _, meet := range step.Meet {
+				fmt.Fprintf(&b, "    %s\n", meet)
+			}
+			for _, change := range step.Changes {
+				if change.Error != "" {
+					fmt.Fprintf(&b, "    %s: %s\n", change.Action, change.Error)
+					continue
+				}
+				fmt.Fprintf(&b, "    %s would change:\n", change.Action)
+				for _, line := range strings.Split(strings.TrimRight(change.Diff, "\n"), "\n") {
+					fmt.Fprintf(&b, "    | %s\n", line)
+				}
+			}
+		case PlanCheck:
+			fmt.Fprintf(&b, "? %s: check once its requirements are met\n", step.Name)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Step returns the step of the Plan for the dep with the given name, or nil
+// if the dep is not in the Plan.
+func (p *Plan) Step(name string) *PlanStep {
+	for _, step := range p.Steps {
+		if step.Name == name {
+			return step
+		}
+	}
+	return nil
+}
+
+// NewPlanner returns a DepVisitor that determines what applying each dep
+// would do, by running its met actions, and previewing its meet actions,
+// without ever running its meet actions. The given options are those of an
+// executor. Once walked, the deps are given to NewPlan.
+//
+// A dep that is met is Satisfied. A dep that is not met, or that requires a
+// dep that is not met, would be met, and is recorded as WouldMeet.
+func NewPlanner(options ...ExecutorOption) DepVisitor {
+	e := &executor{ctx: context.Background()}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	return &planner{executor: e}
+}
+
+// planner is a DepVisitor that records the State of each Dependency it visits
+// as it would be planned. Like a statusChecker, it only ever runs met actions.
+type planner struct {
+
+	// executor runs the met actions, and previews the meet actions
+	*executor
+}
+
+// Visit plans the dep. The met actions of the dep are only run if all of its
+// requirements are already met, as otherwise their outcome may change once
+// the requirements are met.
+func (p *planner) Visit(dep *Dependency) error {
+	if dep.State != Unknown {
+		return fmt.Errorf("plan: dep %s already visited", dep.Name)
+	}
+
+	if err := p.context().Err(); err != nil {
+		p.stop(dep, err)
+		return nil
+	}
+
+	// a dep requiring a dep that cannot be planned cannot be planned either
+	pending := false
+	for _, d := range dep.Dependencies {
+		switch {
+		case d.State == WouldMeet:
+			pending = true
+		case d.State != Satisfied:
+			p.block(dep)
+			return nil
+		}
+	}
+
+	if pending {
+		p.setState(dep, WouldMeet, nil)
+		return nil
+	}
+
+	err := p.runActions(dep, MetPhase, 0, dep.MetActions)
+	switch {
+	case err == nil:
+		p.setState(dep, Satisfied, nil)
+	case isStopped(err):
+		p.stop(dep, err)
+	default:
+		// as for a dry run, the meet actions are previewed as the first
+		// attempt
+		dep.Attempts = 1
+		p.previewActions(dep, dep.Attempts, dep.MeetActions)
+		p.setState(dep, WouldMeet, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package graph
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+)
+
+// plannedGraph returns the deps of a small graph, once walked by a planner:
+// bar is met, baz is not met and foo requires baz.
+func plannedGraph(t *testing.T) (foo, bar, baz *Dependency) {
+	bar = NewDependency("bar")
+	bar.MetActions = []actions.Action{&countingAction{}}
+
+	baz = NewDependency("baz")
+	baz.MetActions = []actions.Action{newFailingAction()}
+	baz.MeetActions = []actions.Action{&previewAction{preview: []byte("-old\n+new\n")}}
+
+	foo = NewDependency("foo")
+	foo.Dependencies = []*Dependency{bar, baz}
+	foo.MetActions = []actions.Action{&countingAction{}}
+
+	if err := NewWalker(NewPlanner()).Walk(graphOf(bar, baz, foo), "foo"); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	return foo, bar, baz
+}
+
+func TestPlanner_Visit(t *testing.T) {
+	foo, bar, baz := plannedGraph(t)
+
+	if bar.State != Satisfied {
+		t.Errorf("wanted bar satisfied; got %s", bar.State)
+	}
+	if baz.State != WouldMeet {
+		t.Errorf("wanted baz would meet; got %s", baz.State)
+	}
+	if foo.State != WouldMeet {
+		t.Errorf("wanted foo would meet; got %s", foo.State)
+	}
+
+	// the meet actions are previewed, but never run
+	if meet := baz.MeetActions[0].(*previewAction); meet.count != 0 {
+		t.Errorf("wanted meet action not run; run %d times", meet.count)
+	}
+
+	// the met actions of foo are not run, as its requirement would be met
+	if met := foo.MetActions[0].(*countingAction); met.count != 0 {
+		t.Errorf("wanted met action of foo not run; run %d times", met.count)
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	foo, _, _ := plannedGraph(t)
+
+	plan, err := NewPlan(Selection{Roots: []string{"foo"}}, "sha256:abc", foo)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	var names []string
+	var planned []PlanAction
+	for _, step := range plan.Steps {
+		names = append(names, step.Name)
+		planned = append(planned, step.Action)
+	}
+	if !reflect.DeepEqual(names, []string{"bar", "baz", "foo"}) {
+		t.Errorf("wanted steps [bar baz foo]; got %v", names)
+	}
+	if !reflect.DeepEqual(planned, []PlanAction{PlanNone, PlanMeet, PlanCheck}) {
+		t.Errorf("wanted actions [none meet check]; got %v", planned)
+	}
+
+	baz := plan.Step("baz")
+	if !reflect.DeepEqual(baz.Meet, []string{"*graph.previewAction"}) {
+		t.Errorf("wanted meet actions of baz; got %v", baz.Meet)
+	}
+	if len(baz.Changes) != 1 || baz.Changes[0].Diff != "-old\n+new\n" {
+		t.Errorf("wanted the change previewed for baz; got %+v", baz.Changes)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := plan.WriteText(buf); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := "  bar: already met\n" +
+		"+ baz: meet\n" +
+		"    *graph.previewAction\n" +
+		"    *graph.previewAction would change:\n" +
+		"    | -old\n" +
+		"    | +new\n" +
+		"? foo: check once its requirements are met\n"
+	if buf.String() != want {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestNewPlan_Unplanned(t *testing.T) {
+	foo := NewDependency("foo")
+	foo.State = Cancelled
+
+	if _, err := NewPlan(Selection{}, "", foo); err == nil {
+		t.Errorf("wanted an error for a dep that was not planned")
+	}
+}
+
+func TestPlan_RoundTrip(t *testing.T) {
+	foo, _, _ := plannedGraph(t)
+
+	plan, err := NewPlan(Selection{Roots: []string{"foo"}, Exclude: []string{"qux"}}, "sha256:abc", foo)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := plan.WriteJSON(buf); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	read, err := ReadPlan(buf)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if !read.Created.Equal(plan.Created) {
+		t.Errorf("wanted created %s; got %s", plan.Created, read.Created)
+	}
+	read.Created = plan.Created
+	if !reflect.DeepEqual(read, plan) {
+		t.Errorf("wanted %+v; got %+v", plan, read)
+	}
+}
+
+func TestReadPlan_UnsupportedVersion(t *testing.T) {
+	_, err := ReadPlan(strings.NewReader(`{"version": 42}`))
+	if err == nil || err.Error() != "plan: unsupported version 42" {
+		t.Errorf("wanted unsupported version error; got %v", err)
+	}
+}
+
+func TestExecutor_Visit_WithPlan(t *testing.T) {
+	plan := &Plan{Steps: []*PlanStep{
+		{Name: "bar", Action: PlanNone},
+		{Name: "baz", Action: PlanMeet},
+		{Name: "foo", Action: PlanCheck},
+	}}
+
+	// bar is no longer met, but was planned as met
+	barMet := newFailingAction()
+	bar := NewDependency("bar")
+	bar.MetActions = []actions.Action{barMet}
+
+	bazMet := &countingAction{}
+	bazMeet := &countingAction{}
+	baz := NewDependency("baz")
+	baz.MetActions = []actions.Action{bazMet}
+	baz.MeetActions = []actions.Action{bazMeet}
+
+	fooMet := &countingAction{}
+	foo := NewDependency("foo")
+	foo.Dependencies = []*Dependency{bar, baz}
+	foo.MetActions = []actions.Action{fooMet}
+
+	e := NewExecutor(WithPlan(plan))
+	for _, dep := range []*Dependency{bar, baz, foo} {
+		if err := e.Visit(dep); err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		if dep.State != Satisfied {
+			t.Errorf("wanted %s satisfied; got %s", dep.Name, dep.State)
+		}
+	}
+
+	if barMet.count != 0 {
+		t.Errorf("wanted met action of bar not run; run %d times", barMet.count)
+	}
+
+	// bar was not verified by the run, so is not a verification of its own
+	if !bar.Cached || baz.Cached || foo.Cached {
+		t.Errorf("wanted only bar cached")
+	}
+
+	// the met actions of baz are only run after the meet actions
+	if bazMeet.count != 1 || bazMet.count != 1 {
+		t.Errorf("wanted meet and met actions of baz run once; run %d and %d times", bazMeet.count, bazMet.count)
+	}
+
+	if fooMet.count != 1 {
+		t.Errorf("wanted met action of foo run once; run %d times", fooMet.count)
+	}
+
+	if err := e.Visit(NewDependency("qux")); err == nil {
+		t.Errorf("wanted an error for a dep not in the plan")
+	}
+}
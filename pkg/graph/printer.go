@@ -5,13 +5,18 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 )
 
 const (
+	// tailLines is the number of lines of output printed for a failed action
+	tailLines = 10
+
 	// ANSI control sequences
-	colorGreen = 92
-	colorRed   = 91
-	escape     = "\x1b"
+	colorGreen  = 92
+	colorRed    = 91
+	colorYellow = 93
+	escape      = "\x1b"
 )
 
 type PrintOption func(*depPrinter)
@@ -21,8 +26,29 @@ var WithColor = func(printer *depPrinter) {
 	printer.colorize = true
 }
 
-// depPrinter is a NodeVisitor that prints out some metadata about each Dep that
-// it visits. The output is indented to represent the dependency graph.
+// Flat is a PrintOption that prints a single, unindented line for each Dep
+// once it has been visited, rather than a nested tree. This is suitable for
+// use with a Walker that visits deps concurrently, where the order in which
+// deps start and finish does not reflect the shape of the graph.
+var Flat = func(printer *depPrinter) {
+	printer.flat = true
+}
+
+// WithWriter returns a PrintOption that prints to the given writer, rather than
+// to Stdout.
+func WithWriter(w io.Writer) PrintOption {
+	return func(printer *depPrinter) {
+		printer.writer = w
+	}
+}
+
+// depPrinter is a Subscriber that prints out some metadata about each Dep as
+// the walk enters and exits it. The output is indented to represent the
+// dependency graph.
+//
+// The printer never evaluates a Dep itself, and only reports the State
+// recorded by the visitor that evaluated it, such as an executor. A Dep that
+// has not been evaluated is printed with its State as unknown.
 type depPrinter struct {
 
 	// indentLevel is the amount to indent each log line
@@ -33,11 +59,15 @@ type depPrinter struct {
 
 	// colorize determines whether to print the output with color
 	colorize bool
+
+	// flat determines whether to print a single line per dep, without
+	// indentation
+	flat bool
 }
 
-// NewDepPrinter returns a new DepVisitor that will print the dependency graph
-// to Stdout.
-func NewDepPrinter(options ...PrintOption) DepVisitor {
+// NewDepPrinter returns a new Subscriber that will print the dependency graph
+// to Stdout, as the Events of a walk are emitted.
+func NewDepPrinter(options ...PrintOption) Subscriber {
 	printer := &depPrinter{writer: os.Stdout}
 
 	for _, option := range options {
@@ -47,30 +77,138 @@ func NewDepPrinter(options ...PrintOption) DepVisitor {
 	return printer
 }
 
-// Visit does nothing.
-func (p *depPrinter) Visit(dep *Dependency) error {
-	return nil
+// Handle prints the tree of deps as the walk enters and exits each dep, or,
+// if the printer is flat, a single line for each dep once it has finished.
+func (p *depPrinter) Handle(event Event) {
+	switch event := event.(type) {
+	case DepEntered:
+		if !p.flat {
+			p.enter(event.Dep)
+		}
+	case DepExited:
+		if !p.flat {
+			p.exit(event.Dep)
+		}
+	case DepFinished:
+		if p.flat {
+			p.print(event.Dep)
+		}
+	}
 }
 
-// PreVisit increments the indentation before printing the pre-visit message.
-func (p *depPrinter) PreVisit(dep *Dependency) {
+// enter increments the indentation after printing the opening line.
+func (p *depPrinter) enter(dep *Dependency) {
 	p.printf("%s {", dep.Name)
 	p.indentLevel++
 }
 
-// PreVisit prints the post-visit message before decrementing the indentation.
-func (p *depPrinter) PostVisit(dep *Dependency) {
+// exit decrements the indentation before printing the closing line.
+func (p *depPrinter) exit(dep *Dependency) {
 	p.indentLevel--
+	p.print(dep)
+}
+
+// print prints the line for the dep, with its State, followed by the reason
+// the dep is unsatisfied, if any.
+func (p *depPrinter) print(dep *Dependency) {
+	var notes []string
+	switch dep.State {
+	case TimedOut, Cancelled, Skipped, WouldMeet:
+		notes = append(notes, dep.State.String())
+	case Blocked:
+		notes = append(notes, fmt.Sprintf("blocked by %s", strings.Join(dep.BlockedBy, ", ")))
+	}
+	if dep.Cached {
+		notes = append(notes, "cached")
+	}
+	if dep.Attempts > 1 {
+		notes = append(notes, fmt.Sprintf("%d attempts", dep.Attempts))
+	}
 
 	var icon string
-	switch isMet(dep) {
-	case true:
+	switch dep.State {
+	case Satisfied:
 		icon = p.green(fmt.Sprintf("✔ %s", dep.Name))
-	case false:
+	case WouldMeet:
+		icon = p.yellow(fmt.Sprintf("~ %s", dep.Name))
+	case Blocked, Skipped:
+		icon = fmt.Sprintf("- %s", dep.Name)
+	case Unknown:
+		icon = fmt.Sprintf("? %s", dep.Name)
+	default:
 		icon = p.red(fmt.Sprintf("✖ %s", dep.Name))
 	}
+	if len(notes) > 0 {
+		icon += fmt.Sprintf(" (%s)", strings.Join(notes, ", "))
+	}
+
+	if p.flat {
+		p.printf("%s", icon)
+	} else {
+		p.printf("} %s", icon)
+	}
+
+	// a blocked or skipped dep was never evaluated, so there is nothing more
+	// to print
+	switch dep.State {
+	case Satisfied, Unknown, Blocked, Skipped:
+	default:
+		p.printFailure(dep)
+		p.printPreviews(dep)
+	}
+}
+
+// printFailure prints the reason the dep is unsatisfied, along with the tail
+// of the output of the action that failed, if any.
+func (p *depPrinter) printFailure(dep *Dependency) {
+	p.indentLevel++
+	defer func() { p.indentLevel-- }()
+
+	if dep.Err != nil {
+		p.printf("%s", dep.Err)
+	}
 
-	p.printf("} %s", icon)
+	failure := dep.Failure()
+	if failure == nil {
+		return
+	}
+
+	for _, line := range tail(failure.Output, tailLines) {
+		p.printf("| %s", line)
+	}
+}
+
+// printPreviews prints the changes that the meet actions of the dep would
+// have made, had they been run.
+func (p *depPrinter) printPreviews(dep *Dependency) {
+	p.indentLevel++
+	defer func() { p.indentLevel-- }()
+
+	for _, preview := range dep.Previews() {
+		if preview.Err != nil {
+			p.printf("%s: %s", preview.Action, preview.Err)
+			continue
+		}
+
+		p.printf("%s would change:", preview.Action)
+		for _, line := range strings.Split(strings.TrimRight(string(preview.Output), "\n"), "\n") {
+			p.printf("| %s", line)
+		}
+	}
+}
+
+// tail returns the last n lines of the given output.
+func tail(output []byte, n int) []string {
+	trimmed := strings.TrimRight(string(output), "\n")
+	if trimmed == "" {
+		return nil
+	}
+
+	lines := strings.Split(trimmed, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
 }
 
 // printf is a simple wrapper around fmt.Println that adds the requisite amount
@@ -88,31 +226,6 @@ func (p *depPrinter) printf(format string, a ...interface{}) {
 	}
 }
 
-// isMet recursively checks the state of the dep to determine the state of the
-// dep.
-func isMet(d *Dependency) bool {
-	if d.State != Unknown {
-		return d.State == Satisfied
-	}
-
-	for _, dep := range d.Dependencies {
-		if !isMet(dep) {
-			d.State = Unsatisfied
-			return false
-		}
-	}
-
-	for _, a := range d.MetActions {
-		if err := a.Run(); err != nil {
-			d.State = Unsatisfied
-			return false
-		}
-	}
-
-	d.State = Satisfied
-	return true
-}
-
 // red returns the given string output as red.
 func (p *depPrinter) red(s string) string {
 	return p.wrap(s, colorRed)
@@ -123,6 +236,11 @@ func (p *depPrinter) green(s string) string {
 	return p.wrap(s, colorGreen)
 }
 
+// yellow returns the given string output as yellow.
+func (p *depPrinter) yellow(s string) string {
+	return p.wrap(s, colorYellow)
+}
+
 // wrap returns the the given string with the given color, unless coloring is
 // disabled.
 func (p *depPrinter) wrap(s string, color int) string {
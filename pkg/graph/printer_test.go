@@ -2,16 +2,20 @@ package graph
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
 )
 
 func TestNewDepPrinter(t *testing.T) {
-	visitor := NewDepPrinter()
+	subscriber := NewDepPrinter()
 
-	printer, ok := visitor.(*depPrinter)
+	printer, ok := subscriber.(*depPrinter)
 	if !ok {
-		t.Fatalf("wanted visitor to be a depPrinter; got %+v", visitor)
+		t.Fatalf("wanted subscriber to be a depPrinter; got %+v", subscriber)
 	}
 
 	if printer.indentLevel != 0 {
@@ -23,12 +27,12 @@ func TestNewDepPrinter(t *testing.T) {
 	}
 }
 
-func TestDepPrinter_PreVisit(t *testing.T) {
+func TestDepPrinter_Handle_DepEntered(t *testing.T) {
 	buf := new(bytes.Buffer)
 	printer := depPrinter{writer: buf}
 
 	dep := NewDependency("foo")
-	printer.PreVisit(dep)
+	printer.Handle(DepEntered{EventHeader{Dep: dep}})
 
 	if printer.indentLevel != 1 {
 		t.Errorf("wanted indentLevel one; got %d", printer.indentLevel)
@@ -40,13 +44,13 @@ func TestDepPrinter_PreVisit(t *testing.T) {
 	}
 }
 
-func TestDepPrinter_PostVisit_IsSatisfied(t *testing.T) {
+func TestDepPrinter_Handle_DepExited_IsSatisfied(t *testing.T) {
 	buf := new(bytes.Buffer)
 	printer := depPrinter{writer: buf, indentLevel: 1}
 
 	dep := NewDependency("foo")
 	dep.State = Satisfied
-	printer.PostVisit(dep)
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
 
 	if printer.indentLevel != 0 {
 		t.Errorf("wanted indentLevel zero; got %d", printer.indentLevel)
@@ -58,13 +62,37 @@ func TestDepPrinter_PostVisit_IsSatisfied(t *testing.T) {
 	}
 }
 
-func TestDepPrinter_PostVisit_IsUnsatisfied(t *testing.T) {
+func TestDepPrinter_Handle_DepExited_IsUnknown(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf, indentLevel: 1}
+
+	dep := NewDependency("foo")
+	metAction := &countingAction{}
+	dep.MetActions = []actions.Action{metAction}
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	wanted := "} ? foo\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+
+	// the printer never evaluates the dep itself
+	if metAction.count != 0 {
+		t.Errorf("wanted met action not called; called %d times", metAction.count)
+	}
+
+	if dep.State != Unknown {
+		t.Errorf("wanted state to remain unknown; got %s", dep.State)
+	}
+}
+
+func TestDepPrinter_Handle_DepExited_IsUnsatisfied(t *testing.T) {
 	buf := new(bytes.Buffer)
 	printer := depPrinter{writer: buf, indentLevel: 1}
 
 	dep := NewDependency("foo")
 	dep.State = Unsatisfied
-	printer.PostVisit(dep)
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
 
 	if printer.indentLevel != 0 {
 		t.Errorf("wanted indentLevel zero; got %d", printer.indentLevel)
@@ -76,6 +104,152 @@ func TestDepPrinter_PostVisit_IsUnsatisfied(t *testing.T) {
 	}
 }
 
+func TestDepPrinter_Handle_DepExited_IsUnsatisfied_PrintsFailure(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf, indentLevel: 1}
+
+	var output []byte
+	for i := 1; i <= tailLines+2; i++ {
+		output = append(output, fmt.Sprintf("line %d\n", i)...)
+	}
+
+	dep := NewDependency("foo")
+	dep.State = Unsatisfied
+	dep.Err = errors.New("meet action failed")
+	dep.Results = []*ActionResult{
+		{Phase: MetPhase, Err: errors.New("met"), Output: []byte("not met\n")},
+		{Phase: MeetPhase, Err: errors.New("meet"), Output: output},
+	}
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	if printer.indentLevel != 0 {
+		t.Errorf("wanted indentLevel zero; got %d", printer.indentLevel)
+	}
+
+	wanted := "} ✖ foo\n  meet action failed\n"
+	for i := 3; i <= tailLines+2; i++ {
+		wanted += fmt.Sprintf("  | line %d\n", i)
+	}
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
+
+func TestDepPrinter_Handle_DepExited_IsUnsatisfied_PrintsPreviews(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf, indentLevel: 1}
+
+	dep := NewDependency("foo")
+	dep.State = Unsatisfied
+	dep.Attempts = 1
+	dep.Err = errors.New("met action failed")
+	dep.Results = []*ActionResult{
+		{Phase: MetPhase, Err: errors.New("met")},
+		{Phase: MeetPhase, Attempt: 1, Action: "[file]: /foo", Preview: true, Output: []byte("-old\n+new\n")},
+		{Phase: MetPhase, Attempt: 1, Err: errors.New("met")},
+	}
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	wanted := "} ✖ foo\n  met action failed\n  [file]: /foo would change:\n  | -old\n  | +new\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
+
+func TestDepPrinter_Handle_DepExited_IsBlocked(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf, indentLevel: 1}
+
+	dep := NewDependency("foo")
+	dep.State = Blocked
+	dep.BlockedBy = []string{"bar", "baz"}
+	dep.Err = errors.New("blocked by requirements bar (failed), baz (failed)")
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	wanted := "} - foo (blocked by bar, baz)\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
+
+func TestDepPrinter_Handle_DepExited_WouldMeet(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf, indentLevel: 1}
+
+	dep := NewDependency("foo")
+	dep.State = WouldMeet
+	dep.Err = errors.New("met action failed")
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	wanted := "} ~ foo (would meet)\n  met action failed\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
+
+func TestDepPrinter_Handle_DepExited_Attempts(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf, indentLevel: 1}
+
+	dep := NewDependency("foo")
+	dep.State = Satisfied
+	dep.Attempts = 3
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	wanted := "} ✔ foo (3 attempts)\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
+
+func TestDepPrinter_Handle_DepFinished_Cached(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf, flat: true}
+
+	dep := NewDependency("foo")
+	dep.State = Satisfied
+	dep.Cached = true
+	printer.Handle(DepFinished{EventHeader{Dep: dep}})
+
+	wanted := "✔ foo (cached)\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
+
+func TestTail(t *testing.T) {
+	if lines := tail([]byte(""), 2); len(lines) != 0 {
+		t.Errorf("wanted no lines; got %+v", lines)
+	}
+
+	lines := tail([]byte("foo\nbar\nbaz\n"), 2)
+	if len(lines) != 2 || lines[0] != "bar" || lines[1] != "baz" {
+		t.Errorf("wanted last two lines; got %+v", lines)
+	}
+}
+
+func TestDepPrinter_Flat(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := depPrinter{writer: buf}
+	Flat(&printer)
+
+	dep := NewDependency("foo")
+	dep.State = Satisfied
+	printer.Handle(DepEntered{EventHeader{Dep: dep}})
+	printer.Handle(DepFinished{EventHeader{Dep: dep}})
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	if printer.indentLevel != 0 {
+		t.Errorf("wanted indentLevel zero; got %d", printer.indentLevel)
+	}
+
+	// a flat printer prints a single line once the dep is finished
+	wanted := "✔ foo\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
+
 func TestDepPrinter_NoColor(t *testing.T) {
 	printer := depPrinter{colorize: false}
 
@@ -117,3 +291,18 @@ func TestDepPrinter_Color(t *testing.T) {
 		}
 	}
 }
+
+func TestDepPrinter_WithWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer := NewDepPrinter(WithWriter(buf))
+
+	dep := NewDependency("foo")
+	dep.State = Satisfied
+	printer.Handle(DepEntered{EventHeader{Dep: dep}})
+	printer.Handle(DepExited{EventHeader{Dep: dep}})
+
+	wanted := "foo {\n} ✔ foo\n"
+	if buf.String() != wanted {
+		t.Errorf("wanted string '%s'; got %s", wanted, buf.String())
+	}
+}
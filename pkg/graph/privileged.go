@@ -0,0 +1,41 @@
+package graph
+
+import (
+	"github.com/nicktrav/matryoshka/pkg/actions"
+)
+
+// PrivilegedDeps returns the names of the deps reachable from the given roots
+// that have actions requiring sudo credentials. The meet actions of each dep
+// are only considered if meet is true, e.g. when not running in dry-run mode.
+func PrivilegedDeps(meet bool, roots ...*Dependency) []string {
+	var names []string
+	visited := make(map[*Dependency]bool)
+
+	var visit func(dep *Dependency)
+	visit = func(dep *Dependency) {
+		if dep == nil || visited[dep] {
+			return
+		}
+		visited[dep] = true
+
+		for _, d := range dep.Dependencies {
+			visit(d)
+		}
+
+		as := dep.MetActions
+		if meet {
+			as = append(append([]actions.Action{}, as...), dep.MeetActions...)
+		}
+		for _, a := range as {
+			if p, ok := a.(actions.Privileged); ok && p.Privileged() {
+				names = append(names, dep.Name)
+				return
+			}
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	return names
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+)
+
+func TestPrivilegedDeps(t *testing.T) {
+	met := NewDependency("met")
+	met.MetActions = []actions.Action{&privilegedAction{privileged: true}}
+
+	meet := NewDependency("meet")
+	meet.MeetActions = []actions.Action{&privilegedAction{privileged: true}}
+
+	unprivileged := NewDependency("unprivileged")
+	unprivileged.MeetActions = []actions.Action{&privilegedAction{}, &countingAction{}}
+
+	root := NewDependency("root")
+	root.Dependencies = []*Dependency{met, meet, unprivileged, met}
+
+	if got, want := PrivilegedDeps(true, root), []string{"met", "meet"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+
+	// meet actions are not considered, e.g. in dry-run mode
+	if got, want := PrivilegedDeps(false, root), []string{"met"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+
+	if got := PrivilegedDeps(true, nil); len(got) != 0 {
+		t.Errorf("wanted no deps; got %v", got)
+	}
+}
+
+// privilegedAction is an Action that may require sudo credentials.
+type privilegedAction struct {
+	privileged bool
+}
+
+func (a *privilegedAction) Run(ctx context.Context) error {
+	return nil
+}
+
+func (a *privilegedAction) Privileged() bool {
+	return a.privileged
+}
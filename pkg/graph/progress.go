@@ -0,0 +1,243 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// progressInterval is the interval at which the progress is redrawn
+	progressInterval = 100 * time.Millisecond
+
+	// defaultWidth is the width assumed for a terminal of unknown width
+	defaultWidth = 80
+)
+
+// spinner is the sequence of frames drawn beside each running dep.
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Progress is a Subscriber that renders the progress of a walk to a terminal.
+// Each running dep is drawn with a spinner, the time elapsed since it started
+// and the action currently running, and is replaced with a single line, as
+// printed by a flat DepPrinter, once it completes. A summary is printed once
+// the walk is stopped, with the number of deps in each State.
+//
+// A dep is drawn as running from its DepStarted until its DepFinished, with
+// the action of its latest ActionStarted, hence the executor of the walk
+// should emit to the same Events. The methods of Progress are safe to call
+// concurrently.
+type Progress struct {
+
+	// mu guards the fields below
+	mu sync.Mutex
+
+	// writer is the terminal to draw to
+	writer io.Writer
+
+	// width is the width of the terminal, in columns
+	width int
+
+	// printer prints each completed dep to buf
+	printer *depPrinter
+
+	// buf holds the lines printed for the deps completed since the last draw
+	buf bytes.Buffer
+
+	// running are the deps currently being visited, in the order in which
+	// they started
+	running []*runningDep
+
+	// lines is the number of lines of running deps last drawn
+	lines int
+
+	// frame is the current frame of the spinner
+	frame int
+
+	// start is the time at which the walk started
+	start time.Time
+
+	// completed are the deps that have completed, in the order in which
+	// they completed
+	completed []*Dependency
+
+	// stop is closed to stop redrawing, and done is closed once stopped
+	stop, done chan struct{}
+
+	// now returns the current time
+	now func() time.Time
+}
+
+// runningDep is a dep that is currently being visited.
+type runningDep struct {
+
+	// dep is the dep being visited
+	dep *Dependency
+
+	// start is the time at which the visit started
+	start time.Time
+
+	// action describes the action currently running, if any
+	action string
+}
+
+// NewProgress returns a new Progress that draws to the given terminal, of the
+// given width. The given PrintOptions apply to the lines printed for the deps
+// that have completed.
+func NewProgress(w io.Writer, width int, options ...PrintOption) *Progress {
+	if width <= 0 {
+		width = defaultWidth
+	}
+
+	p := &Progress{
+		writer: w,
+		width:  width,
+		now:    time.Now,
+	}
+
+	p.printer = &depPrinter{}
+	for _, option := range options {
+		option(p.printer)
+	}
+	p.printer.writer = &p.buf
+	p.printer.flat = true
+
+	return p
+}
+
+// Start starts redrawing the running deps at a regular interval, until Stop
+// is called.
+func (p *Progress) Start() {
+	p.mu.Lock()
+	p.start = p.now()
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	p.mu.Unlock()
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.frame = (p.frame + 1) % len(spinner)
+				p.draw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing, and prints a summary of the deps that completed.
+func (p *Progress) Stop() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.running = nil
+	p.draw()
+
+	// the completed deps were walked, so cannot contain a cycle
+	summary, _ := Summarize(p.completed...)
+	fmt.Fprintf(p.writer, "%s in %s\n", summary.Totals(), p.now().Sub(p.start).Round(time.Millisecond))
+}
+
+// Handle marks a dep as running once it has started, records the action
+// currently running on it, and prints the line for the dep once it has
+// finished.
+func (p *Progress) Handle(event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch event := event.(type) {
+	case DepStarted:
+		p.running = append(p.running, &runningDep{dep: event.Dep, start: p.now()})
+		p.draw()
+	case ActionStarted:
+		if i := p.indexOf(event.Dep); i >= 0 {
+			p.running[i].action = event.Action
+		}
+	case DepFinished:
+		i := p.indexOf(event.Dep)
+		if i < 0 {
+			return
+		}
+		p.running = append(p.running[:i], p.running[i+1:]...)
+		p.completed = append(p.completed, event.Dep)
+
+		p.printer.print(event.Dep)
+		p.draw()
+	}
+}
+
+// indexOf returns the index of the given dep in the running deps, or -1 if
+// the dep is not running.
+func (p *Progress) indexOf(dep *Dependency) int {
+	for i, r := range p.running {
+		if r.dep == dep {
+			return i
+		}
+	}
+	return -1
+}
+
+// draw erases the running deps last drawn, prints the lines for any deps that
+// have since completed, and draws the running deps. The caller must hold mu.
+func (p *Progress) draw() {
+	var b strings.Builder
+
+	// move up to the first line last drawn, and clear to the end of the
+	// screen
+	if p.lines > 0 {
+		fmt.Fprintf(&b, "%s[%dA%s[J", escape, p.lines, escape)
+	}
+
+	b.Write(p.buf.Bytes())
+	p.buf.Reset()
+
+	now := p.now()
+	for _, r := range p.running {
+		line := fmt.Sprintf("%s %s %s", spinner[p.frame], r.dep.Name, now.Sub(r.start).Round(time.Second))
+		if r.action != "" {
+			line += "  " + r.action
+		}
+		b.WriteString(truncate(line, p.width-1))
+		b.WriteString("\n")
+	}
+	p.lines = len(p.running)
+
+	// there is nothing to be done if the terminal has gone away
+	_, _ = io.WriteString(p.writer, b.String())
+}
+
+// truncate returns the first line of the given string, truncated to at most
+// the given number of characters, such that it fits on a single line of the
+// terminal.
+func truncate(s string, n int) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+	if n <= 1 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+)
+
+// newTestProgress returns a Progress drawing to the returned buffer, with a
+// clock that is advanced by the returned function.
+func newTestProgress(width int) (*Progress, *bytes.Buffer, func(time.Duration)) {
+	buf := new(bytes.Buffer)
+	p := NewProgress(buf, width)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	p.start = now
+
+	return p, buf, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestProgress_DrawsRunningDeps(t *testing.T) {
+	p, buf, advance := newTestProgress(80)
+
+	foo := NewDependency("foo")
+	p.Handle(DepStarted{EventHeader{Dep: foo}})
+	p.Handle(ActionStarted{EventHeader: EventHeader{Dep: foo}, Phase: MeetPhase, Action: "*graph.countingAction"})
+
+	advance(3 * time.Second)
+	buf.Reset()
+	p.draw()
+
+	// the line last drawn is erased, and redrawn with the running action
+	want := escape + "[1A" + escape + "[J" + "⠋ foo 3s  *graph.countingAction\n"
+	if buf.String() != want {
+		t.Errorf("wanted %q; got %q", want, buf.String())
+	}
+}
+
+func TestProgress_PrintsCompletedDeps(t *testing.T) {
+	p, buf, _ := newTestProgress(80)
+
+	foo := NewDependency("foo")
+	bar := NewDependency("bar")
+	p.Handle(DepStarted{EventHeader{Dep: foo}})
+	p.Handle(DepStarted{EventHeader{Dep: bar}})
+
+	buf.Reset()
+	foo.State = Satisfied
+	p.Handle(DepFinished{EventHeader{Dep: foo}})
+
+	// both running deps are erased, and the completed dep printed above the
+	// dep still running
+	want := escape + "[2A" + escape + "[J" + "✔ foo\n" + "⠋ bar 0s\n"
+	if buf.String() != want {
+		t.Errorf("wanted %q; got %q", want, buf.String())
+	}
+
+	// a dep that is no longer running is not printed again
+	buf.Reset()
+	p.Handle(DepFinished{EventHeader{Dep: foo}})
+	if buf.Len() != 0 {
+		t.Errorf("wanted nothing drawn; got %q", buf.String())
+	}
+}
+
+func TestProgress_WithWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	other := new(bytes.Buffer)
+	p := NewProgress(buf, 80, WithWriter(other))
+
+	foo := NewDependency("foo")
+	foo.State = Satisfied
+	p.Handle(DepStarted{EventHeader{Dep: foo}})
+	p.Handle(DepFinished{EventHeader{Dep: foo}})
+
+	// completed deps are always drawn to the terminal of the Progress
+	if !strings.Contains(buf.String(), "✔ foo\n") || other.Len() != 0 {
+		t.Errorf("wanted foo drawn to the terminal; got %q and %q", buf.String(), other.String())
+	}
+}
+
+func TestProgress_Stop_PrintsSummary(t *testing.T) {
+	p, buf, advance := newTestProgress(80)
+
+	satisfied := NewDependency("satisfied")
+	failed := NewDependency("failed")
+	blocked := NewDependency("blocked")
+	blocked.Dependencies = []*Dependency{failed}
+	for _, dep := range []*Dependency{satisfied, failed, blocked} {
+		p.Handle(DepStarted{EventHeader{Dep: dep}})
+	}
+
+	satisfied.State = Satisfied
+	failed.State = Failed
+	blocked.State = Blocked
+
+	for _, dep := range []*Dependency{satisfied, failed, blocked} {
+		p.Handle(DepFinished{EventHeader{Dep: dep}})
+	}
+
+	advance(65 * time.Second)
+	buf.Reset()
+	p.Stop()
+
+	want := "1 satisfied, 1 failed, 1 blocked in 1m5s\n"
+	if buf.String() != want {
+		t.Errorf("wanted %q; got %q", want, buf.String())
+	}
+}
+
+func TestProgress_StartStop(t *testing.T) {
+	buf := new(bytes.Buffer)
+	p := NewProgress(buf, 80)
+
+	p.Start()
+	p.Handle(DepStarted{EventHeader{Dep: NewDependency("foo")}})
+	p.Stop()
+
+	// the running dep is erased, leaving only the summary
+	if !strings.Contains(buf.String(), escape+"[1A"+escape+"[J0 satisfied in ") {
+		t.Errorf("wanted running dep erased and summary printed; got %q", buf.String())
+	}
+}
+
+func TestProgress_WithExecutor(t *testing.T) {
+	buf := new(bytes.Buffer)
+	p := NewProgress(buf, 80)
+
+	foo := NewDependency("foo")
+	foo.MetActions = []actions.Action{&countingAction{}}
+
+	var started []Phase
+	events := NewEvents(p, SubscriberFunc(func(event Event) {
+		if event, ok := event.(ActionStarted); ok {
+			started = append(started, event.Phase)
+		}
+	}))
+
+	v := events.Visitor(NewExecutor(WithEvents(events)))
+	if err := NewWalker(v).Walk(graphOf(foo), "foo"); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if len(started) != 1 || started[0] != MetPhase {
+		t.Errorf("wanted the met action started; got %v", started)
+	}
+
+	if !strings.Contains(buf.String(), "✔ foo\n") {
+		t.Errorf("wanted foo printed as satisfied; got %q", buf.String())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	testCases := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"too long", 5, "too …"},
+		{"⠋ über", 4, "⠋ ü…"},
+		{"first\nsecond", 10, "first"},
+	}
+
+	for _, tc := range testCases {
+		if got := truncate(tc.s, tc.n); got != tc.want {
+			t.Errorf("truncate(%q, %d): wanted %q; got %q", tc.s, tc.n, tc.want, got)
+		}
+	}
+}
+
+// graphOf returns a DependencyGraph containing the given deps.
+func graphOf(deps ...*Dependency) *DependencyGraph {
+	g := NewDependencyGraph()
+	for _, dep := range deps {
+		g.depMap[dep.Name] = dep
+		g.deps = append(g.deps, dep)
+	}
+	return g
+}
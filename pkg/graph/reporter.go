@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Report is a machine-readable record of the outcome of a walk of the
+// DependencyGraph.
+type Report struct {
+
+	// Start is the time at which the first dep was started.
+	Start time.Time `json:"start"`
+
+	// End is the time at which the last dep was finished.
+	End time.Time `json:"end"`
+
+	// Deps are the deps visited, in the order in which they were completed.
+	Deps []*DepReport `json:"deps"`
+}
+
+// DepReport records the outcome of visiting a single Dependency.
+type DepReport struct {
+
+	// Name is the name of the dep.
+	Name string `json:"name"`
+
+	// Definition is the digest of the definition of the dep.
+	Definition string `json:"definition,omitempty"`
+
+	// Closure is the digest of the definition of the dep, along with those
+	// of the deps it requires.
+	Closure string `json:"closure,omitempty"`
+
+	// State is the final state of the dep.
+	State State `json:"state"`
+
+	// Error is the reason the dep is unsatisfied, if any.
+	Error string `json:"error,omitempty"`
+
+	// BlockedBy are the names of the requirements that were not satisfied,
+	// if the dep is blocked.
+	BlockedBy []string `json:"blocked_by,omitempty"`
+
+	// Attempts is the number of times the meet actions were attempted.
+	Attempts int `json:"attempts"`
+
+	// Cached is true if the dep was satisfied without being evaluated, as it
+	// was recently verified, or was verified when the plan applied was made.
+	Cached bool `json:"cached,omitempty"`
+
+	// Start is the time at which the dep was started.
+	Start time.Time `json:"start"`
+
+	// DurationSeconds is the length of time spent visiting the dep, once
+	// its requirements were visited.
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// Actions are the actions run on the dep, in the order they were run.
+	Actions []*ActionReport `json:"actions"`
+}
+
+// ActionReport records the outcome of running a single Action.
+type ActionReport struct {
+
+	// Phase is the phase in which the action was run, i.e. "met" or "meet".
+	Phase Phase `json:"phase"`
+
+	// Attempt is the attempt during which the action was run, or zero for
+	// the initial met actions.
+	Attempt int `json:"attempt"`
+
+	// Action is a description of the action.
+	Action string `json:"action"`
+
+	// ExitCode is the exit code of the action.
+	ExitCode int `json:"exit_code"`
+
+	// Error is the error returned by the action, if any.
+	Error string `json:"error,omitempty"`
+
+	// Start is the time at which the action started running.
+	Start time.Time `json:"start"`
+
+	// DurationSeconds is the length of time the action took to run.
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// Output is the output captured while running the action, or the
+	// changes the action would have made for a preview.
+	Output string `json:"output"`
+
+	// Preview is true if the action was previewed rather than run.
+	Preview bool `json:"preview,omitempty"`
+}
+
+// Reporter is a Subscriber that records the outcome of each Dependency once it
+// has finished, such that a Report can be written once the walk has completed.
+type Reporter struct {
+
+	// report is the report being built
+	report Report
+
+	// started is the time at which each dep was started
+	started map[*Dependency]time.Time
+
+	// reported is the set of deps that have been added to the report
+	reported map[*Dependency]bool
+
+	// closures caches the Closure of each dep reported
+	closures map[*Dependency]string
+}
+
+// NewReporter returns a new Reporter.
+func NewReporter() *Reporter {
+	return &Reporter{
+		report:   Report{Deps: []*DepReport{}},
+		started:  make(map[*Dependency]time.Time),
+		reported: make(map[*Dependency]bool),
+		closures: make(map[*Dependency]string),
+	}
+}
+
+// Handle records the time at which each dep is started, and adds the dep to
+// the report once it has finished.
+func (r *Reporter) Handle(event Event) {
+	switch event := event.(type) {
+	case DepStarted:
+		r.start(event.Dep, event.Time)
+	case DepFinished:
+		r.finish(event.Dep, event.Time)
+	}
+}
+
+// start records the time at which the dep was started.
+func (r *Reporter) start(dep *Dependency, now time.Time) {
+	if r.report.Start.IsZero() {
+		r.report.Start = now
+	}
+	if _, ok := r.started[dep]; !ok {
+		r.started[dep] = now
+	}
+}
+
+// finish adds the dep to the report, the first time it has finished.
+func (r *Reporter) finish(dep *Dependency, now time.Time) {
+	if r.reported[dep] {
+		return
+	}
+	r.reported[dep] = true
+	r.report.End = now
+
+	start, ok := r.started[dep]
+	if !ok {
+		start = now
+	}
+	depReport := &DepReport{
+		Name:            dep.Name,
+		Definition:      dep.Definition,
+		Closure:         closure(dep, r.closures),
+		State:           dep.State,
+		BlockedBy:       dep.BlockedBy,
+		Attempts:        dep.Attempts,
+		Cached:          dep.Cached,
+		Start:           start,
+		DurationSeconds: now.Sub(start).Seconds(),
+		Actions:         []*ActionReport{},
+	}
+	if dep.Err != nil {
+		depReport.Error = dep.Err.Error()
+	}
+
+	for _, result := range dep.Results {
+		actionReport := &ActionReport{
+			Phase:           result.Phase,
+			Attempt:         result.Attempt,
+			Action:          result.Action,
+			ExitCode:        result.ExitCode,
+			Start:           result.Start,
+			DurationSeconds: result.Duration.Seconds(),
+			Output:          string(result.Output),
+			Preview:         result.Preview,
+		}
+		if result.Err != nil {
+			actionReport.Error = result.Err.Error()
+		}
+		depReport.Actions = append(depReport.Actions, actionReport)
+	}
+
+	r.report.Deps = append(r.report.Deps, depReport)
+}
+
+// Report returns the report of the deps visited so far.
+func (r *Reporter) Report() *Report {
+	return &r.report
+}
+
+// WriteJSON writes the report of the deps visited so far to the given writer,
+// as JSON.
+func (r *Reporter) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.report)
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReporter_Handle(t *testing.T) {
+	reporter := NewReporter()
+
+	dep := NewDependency("foo")
+	dep.State = Unsatisfied
+	dep.Err = errors.New("met action failed")
+	dep.Results = []*ActionResult{
+		{Phase: MetPhase, Action: "met", ExitCode: 1, Err: errors.New("oh noes"), Output: []byte("nope")},
+		{Phase: MeetPhase, Action: "meet", ExitCode: 0},
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	reporter.Handle(DepStarted{EventHeader{Time: start, Dep: dep}})
+	reporter.Handle(DepFinished{EventHeader{Time: start.Add(2 * time.Second), Dep: dep}})
+
+	// finishing the dep a second time does not add it to the report again
+	reporter.Handle(DepFinished{EventHeader{Time: start.Add(3 * time.Second), Dep: dep}})
+
+	report := reporter.Report()
+	if len(report.Deps) != 1 {
+		t.Fatalf("wanted one dep in the report; got %d", len(report.Deps))
+	}
+
+	depReport := report.Deps[0]
+	if depReport.Name != "foo" {
+		t.Errorf("wanted dep name foo; got %s", depReport.Name)
+	}
+
+	if !depReport.Start.Equal(start) || depReport.DurationSeconds != 2 {
+		t.Errorf("wanted dep started at %s for 2s; got %s for %vs", start, depReport.Start, depReport.DurationSeconds)
+	}
+
+	if depReport.State != Unsatisfied {
+		t.Errorf("wanted state unsatisfied; got %s", depReport.State)
+	}
+
+	if depReport.Error != "met action failed" {
+		t.Errorf("wanted dep error in the report; got '%s'", depReport.Error)
+	}
+
+	if len(depReport.Actions) != 2 {
+		t.Fatalf("wanted two actions in the report; got %d", len(depReport.Actions))
+	}
+
+	met := depReport.Actions[0]
+	if met.Phase != MetPhase || met.ExitCode != 1 || met.Error != "oh noes" || met.Output != "nope" {
+		t.Errorf("wanted failed met action in the report; got %+v", met)
+	}
+
+	meet := depReport.Actions[1]
+	if meet.Phase != MeetPhase || meet.ExitCode != 0 || meet.Error != "" {
+		t.Errorf("wanted successful meet action in the report; got %+v", meet)
+	}
+}
+
+func TestReporter_WriteJSON(t *testing.T) {
+	graph := newGraph()
+
+	reporter := NewReporter()
+	events := NewEvents(reporter)
+	walker := NewWalker(events.Visitor(NewExecutor(WithEvents(events))))
+	if err := walker.Walk(graph, "foo"); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := reporter.WriteJSON(buf); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	var decoded struct {
+		Deps []struct {
+			Name  string `json:"name"`
+			State string `json:"state"`
+		} `json:"deps"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("could not decode report: %+v", err)
+	}
+
+	// deps are reported in the order in which they were completed
+	expectedOrder := []string{"baz", "boom", "bar", "bam", "foo"}
+	if len(decoded.Deps) != len(expectedOrder) {
+		t.Fatalf("wanted %d deps in the report; got %d", len(expectedOrder), len(decoded.Deps))
+	}
+
+	for i, want := range expectedOrder {
+		got := decoded.Deps[i]
+		if got.Name != want {
+			t.Errorf("wanted dep #%d to be %s; got %s", i, want, got.Name)
+		}
+		if got.State != "satisfied" {
+			t.Errorf("wanted dep %s to be satisfied; got %s", got.Name, got.State)
+		}
+	}
+}
+
+func TestReporter_WriteJSON_Empty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewReporter().WriteJSON(buf); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte(`"deps": []`)) {
+		t.Errorf("wanted an empty list of deps; got %s", buf.String())
+	}
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// Selection determines the deps of a DependencyGraph to walk.
+type Selection struct {
+
+	// Roots are the names of the deps to walk from.
+	Roots []string `json:"roots,omitempty"`
+
+	// Tags select every dep with any of the tags as an additional root.
+	Tags []string `json:"tags,omitempty"`
+
+	// Exclude are the names of the deps to skip. The requirements of an
+	// excluded dep are only walked if they are required by another dep that
+	// is walked.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Select removes the excluded deps from the graph, including from the
+// requirements of the remaining deps, and returns the names of the roots to
+// walk. The roots are the named deps, in the order given, followed by the deps
+// with any of the tags, in the order in which they were added to the graph.
+// Excluded deps are never roots.
+//
+// An error is returned if a named dep, including an excluded dep, is not in
+// the graph, or if no dep has one of the tags.
+func (g *DependencyGraph) Select(s Selection) ([]string, error) {
+	for _, name := range append(append([]string{}, s.Roots...), s.Exclude...) {
+		if g.Get(name) == nil {
+			return nil, fmt.Errorf("dep %s not found", name)
+		}
+	}
+
+	excluded := make(map[string]bool)
+	for _, name := range s.Exclude {
+		excluded[name] = true
+	}
+
+	var roots []string
+	selected := make(map[string]bool)
+	add := func(name string) {
+		if !excluded[name] && !selected[name] {
+			selected[name] = true
+			roots = append(roots, name)
+		}
+	}
+
+	for _, name := range s.Roots {
+		add(name)
+	}
+
+	for _, tag := range s.Tags {
+		found := false
+		for _, dep := range g.deps {
+			if hasTag(dep, tag) {
+				found = true
+				add(dep.Name)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no deps tagged %s", tag)
+		}
+	}
+
+	if len(excluded) > 0 {
+		g.remove(excluded)
+	}
+
+	return roots, nil
+}
+
+// remove removes the deps with the given names from the graph, along with any
+// requirement on them.
+func (g *DependencyGraph) remove(names map[string]bool) {
+	var deps []*Dependency
+	for _, dep := range g.deps {
+		if names[dep.Name] {
+			delete(g.depMap, dep.Name)
+			continue
+		}
+
+		var requirements []*Dependency
+		for _, d := range dep.Dependencies {
+			if !names[d.Name] {
+				requirements = append(requirements, d)
+			}
+		}
+		dep.Dependencies = requirements
+
+		deps = append(deps, dep)
+	}
+	g.deps = deps
+}
+
+// hasTag returns true if the given dep has the given tag.
+func hasTag(dep *Dependency, tag string) bool {
+	for _, t := range dep.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/lang"
+)
+
+// newTaggedGraph returns a new graph of the form:
+//
+//	all -> editor -> config
+//	    -> go -> config
+//
+// where editor is tagged "editor", and go is tagged "lang" and "lang-go".
+func newTaggedGraph(t *testing.T) *DependencyGraph {
+	config := &lang.Dep{Name: "config", Enable: true}
+	editor := &lang.Dep{Name: "editor", Tags: []string{"editor"}, Requirements: []*lang.Dep{config}, Enable: true}
+	golang := &lang.Dep{Name: "go", Tags: []string{"lang", "lang-go"}, Requirements: []*lang.Dep{config}, Enable: true}
+	all := &lang.Dep{Name: "all", Requirements: []*lang.Dep{editor, golang}, Enable: true}
+
+	g := NewDependencyGraph()
+	if err := g.Construct([]*lang.Dep{all}); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+	return g
+}
+
+func TestDependencyGraph_Select(t *testing.T) {
+	testCases := []struct {
+		name      string
+		selection Selection
+		want      []string
+	}{
+		{
+			name:      "roots",
+			selection: Selection{Roots: []string{"go", "editor"}},
+			want:      []string{"go", "editor"},
+		},
+		{
+			name:      "tags",
+			selection: Selection{Tags: []string{"lang-go", "editor"}},
+			want:      []string{"go", "editor"},
+		},
+		{
+			name:      "roots and tags, without duplicates",
+			selection: Selection{Roots: []string{"go"}, Tags: []string{"lang", "editor"}},
+			want:      []string{"go", "editor"},
+		},
+		{
+			name:      "excluded roots",
+			selection: Selection{Roots: []string{"go"}, Tags: []string{"editor"}, Exclude: []string{"editor"}},
+			want:      []string{"go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		g := newTaggedGraph(t)
+
+		got, err := g.Select(tc.selection)
+		if err != nil {
+			t.Errorf("%s: got error: %+v", tc.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: wanted roots %v; got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestDependencyGraph_Select_Exclude(t *testing.T) {
+	g := newTaggedGraph(t)
+
+	roots, err := g.Select(Selection{Roots: []string{"all"}, Exclude: []string{"editor"}})
+	if err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	// the excluded dep is removed from the graph, and from the requirements
+	// of the deps that required it
+	if g.Get("editor") != nil {
+		t.Error("wanted editor removed from the graph")
+	}
+
+	tracker := newTracker()
+	if err := NewWalker(tracker).Walk(g, roots...); err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	var got []string
+	for _, dep := range tracker.depsVisited {
+		got = append(got, dep.Name)
+	}
+
+	// config is still required by go
+	want := []string{"config", "go", "all"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted walk %v; got %v", want, got)
+	}
+}
+
+func TestDependencyGraph_Select_Errors(t *testing.T) {
+	testCases := map[string]Selection{
+		"missing root":     {Roots: []string{"missing"}},
+		"missing excluded": {Roots: []string{"all"}, Exclude: []string{"missing"}},
+		"unknown tag":      {Tags: []string{"missing"}},
+	}
+
+	for name, selection := range testCases {
+		if _, err := newTaggedGraph(t).Select(selection); err == nil {
+			t.Errorf("%s: wanted error; got none", name)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"sort"
+)
+
+// Sources returns the paths of the files read by the actions of the deps
+// reachable from the given roots when they are run, sorted and without
+// duplicates.
+func Sources(roots ...*Dependency) []string {
+	var sources []string
+	seen := make(map[string]bool)
+	visited := make(map[*Dependency]bool)
+
+	var visit func(dep *Dependency)
+	visit = func(dep *Dependency) {
+		if dep == nil || visited[dep] {
+			return
+		}
+		visited[dep] = true
+
+		for _, d := range dep.Dependencies {
+			visit(d)
+		}
+
+		for _, source := range dep.Sources {
+			if !seen[source] {
+				seen[source] = true
+				sources = append(sources, source)
+			}
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	sort.Strings(sources)
+	return sources
+}
@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSources(t *testing.T) {
+	bar := NewDependency("bar")
+	bar.Sources = []string{"/b.tmpl", "/a.tmpl"}
+
+	baz := NewDependency("baz")
+	baz.Sources = []string{"/c.tmpl"}
+
+	foo := NewDependency("foo")
+	foo.Sources = []string{"/b.tmpl"}
+	foo.Dependencies = []*Dependency{bar}
+
+	// baz is not reachable from foo
+	if got, want := Sources(foo), []string{"/a.tmpl", "/b.tmpl"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+
+	if got := Sources(nil); len(got) != 0 {
+		t.Errorf("wanted no sources; got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+)
+
+// NewStatusChecker returns a DepVisitor that determines whether each dep is
+// satisfied by running its met actions, without ever running its meet
+// actions. The given options are those of an executor. DryRun has no effect,
+// as no meet actions are run, or previewed.
+func NewStatusChecker(options ...ExecutorOption) DepVisitor {
+	e := &executor{ctx: context.Background()}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	return &statusChecker{executor: e}
+}
+
+// statusChecker is a DepVisitor that records the State of each Dependency it
+// visits, as determined by the met actions of the Dependency. It is read-only,
+// in that it only ever runs met actions.
+type statusChecker struct {
+
+	// executor runs the met actions, recording their results
+	*executor
+}
+
+// Visit determines the state of the dep. The dep is blocked if any of its
+// dependencies are not satisfied, in which case its met actions are not run.
+// Otherwise, the dep is satisfied if its met actions all succeed.
+//
+// As for an executor, the state of the dep should be unknown at the time of
+// visiting.
+func (s *statusChecker) Visit(dep *Dependency) error {
+	if dep.State != Unknown {
+		return fmt.Errorf("status: dep %s already visited", dep.Name)
+	}
+
+	if err := s.context().Err(); err != nil {
+		s.stop(dep, err)
+		return nil
+	}
+
+	if s.block(dep) {
+		return nil
+	}
+
+	err := s.runActions(dep, MetPhase, 0, dep.MetActions)
+	switch {
+	case err == nil:
+		s.setState(dep, Satisfied, nil)
+	case isStopped(err):
+		s.stop(dep, err)
+	default:
+		s.setState(dep, Unsatisfied, err)
+	}
+
+	return nil
+}
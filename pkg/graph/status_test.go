@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nicktrav/matryoshka/pkg/actions"
+)
+
+func TestStatusChecker_Visit_StateNotUnknown(t *testing.T) {
+	fooDep := NewDependency("foo")
+	fooDep.State = Satisfied
+
+	if err := NewStatusChecker().Visit(fooDep); err == nil {
+		t.Error("wanted error for dep already visited")
+	}
+}
+
+func TestStatusChecker_Visit_MetActionsSatisfied(t *testing.T) {
+	fooDep := NewDependency("foo")
+	metAction := &countingAction{}
+	meetAction := &countingAction{}
+	fooDep.MetActions = []actions.Action{metAction}
+	fooDep.MeetActions = []actions.Action{meetAction}
+
+	if err := NewStatusChecker().Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Satisfied {
+		t.Errorf("wanted state satisfied; got %s", fooDep.State)
+	}
+
+	if metAction.count != 1 {
+		t.Errorf("wanted met action called once; called %d times", metAction.count)
+	}
+
+	if meetAction.count != 0 {
+		t.Errorf("wanted meet action not called; called %d times", meetAction.count)
+	}
+}
+
+func TestStatusChecker_Visit_MetActionsUnsatisfied(t *testing.T) {
+	fooDep := NewDependency("foo")
+	fooDep.MetActions = []actions.Action{newFailingAction()}
+	meetAction := &previewAction{preview: []byte("a change")}
+	fooDep.MeetActions = []actions.Action{meetAction}
+
+	// meet actions are neither run nor previewed, even in dry-run mode
+	if err := NewStatusChecker(DryRun).Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Unsatisfied {
+		t.Errorf("wanted state unsatisfied; got %s", fooDep.State)
+	}
+
+	if fooDep.Err == nil {
+		t.Error("wanted error recorded for the failed met action")
+	}
+
+	if meetAction.count != 0 {
+		t.Errorf("wanted meet action not called; called %d times", meetAction.count)
+	}
+
+	if len(fooDep.Results) != 1 || fooDep.Results[0].Phase != MetPhase {
+		t.Errorf("wanted a single met result; got %+v", fooDep.Results)
+	}
+}
+
+func TestStatusChecker_Visit_DepsUnsatisfied(t *testing.T) {
+	barDep := NewDependency("bar")
+	barDep.State = Unsatisfied
+
+	fooDep := NewDependency("foo")
+	fooDep.Dependencies = []*Dependency{barDep}
+	metAction := &countingAction{}
+	fooDep.MetActions = []actions.Action{metAction}
+
+	if err := NewStatusChecker().Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Blocked {
+		t.Errorf("wanted state blocked; got %s", fooDep.State)
+	}
+
+	if metAction.count != 0 {
+		t.Errorf("wanted met action not called; called %d times", metAction.count)
+	}
+}
+
+func TestStatusChecker_Visit_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fooDep := NewDependency("foo")
+	metAction := &countingAction{}
+	fooDep.MetActions = []actions.Action{metAction}
+
+	if err := NewStatusChecker(WithContext(ctx)).Visit(fooDep); err != nil {
+		t.Fatalf("wanted no error; got %+v", err)
+	}
+
+	if fooDep.State != Cancelled {
+		t.Errorf("wanted state cancelled; got %s", fooDep.State)
+	}
+
+	if metAction.count != 0 {
+		t.Errorf("wanted met action not called; called %d times", metAction.count)
+	}
+}
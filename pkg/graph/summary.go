@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Summary summarises the outcome of a walk: the number of deps in each State,
+// and the deps that are the root causes of any deps not being satisfied.
+type Summary struct {
+
+	// Counts is the number of deps in each State.
+	Counts map[State]int
+
+	// RootCauses are the deps that failed, timed out, were cancelled or were
+	// found to be unsatisfied, in the order in which they were walked.
+	RootCauses []*RootCause
+}
+
+// RootCause is a dep that is not satisfied, other than as a consequence of a
+// requirement not being satisfied.
+type RootCause struct {
+
+	// Dep is the dep that is not satisfied.
+	Dep *Dependency
+
+	// Blocks are the names of the deps blocked, directly or transitively, by
+	// the dep, in the order in which they were walked.
+	Blocks []string
+}
+
+// Summarize returns a Summary of the given deps, along with the requirements
+// reachable from them, once they have been walked. An error is returned if
+// the deps contain a cycle.
+func Summarize(roots ...*Dependency) (*Summary, error) {
+	deps, err := postOrder(roots...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Summary{Counts: make(map[State]int)}
+	causes := make(map[*Dependency]*RootCause)
+	for _, dep := range deps {
+		s.Counts[dep.State]++
+
+		if dep.State.RootCause() {
+			cause := &RootCause{Dep: dep}
+			causes[dep] = cause
+			s.RootCauses = append(s.RootCauses, cause)
+		}
+	}
+
+	// requirements are walked before the deps requiring them, so the root
+	// causes of every requirement are known by the time a dep is blocked
+	blockedBy := make(map[*Dependency][]*Dependency)
+	for _, dep := range deps {
+		if dep.State.RootCause() {
+			blockedBy[dep] = []*Dependency{dep}
+			continue
+		}
+		if dep.State != Blocked {
+			continue
+		}
+
+		seen := make(map[*Dependency]bool)
+		for _, req := range dep.Dependencies {
+			for _, cause := range blockedBy[req] {
+				if !seen[cause] {
+					seen[cause] = true
+					blockedBy[dep] = append(blockedBy[dep], cause)
+					causes[cause].Blocks = append(causes[cause].Blocks, dep.Name)
+				}
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Totals returns the number of deps in each State, e.g. "3 satisfied,
+// 1 failed, 2 blocked", as formatted by FormatTotals.
+func (s *Summary) Totals() string {
+	return FormatTotals(s.Counts)
+}
+
+// FormatTotals returns the given number of deps in each State, e.g.
+// "3 satisfied, 1 failed, 2 blocked". The number of satisfied deps is always
+// included, followed by any other State with at least one dep.
+func FormatTotals(counts map[State]int) string {
+	totals := []string{fmt.Sprintf("%d %s", counts[Satisfied], Satisfied)}
+	for _, state := range States {
+		if state != Satisfied && counts[state] > 0 {
+			totals = append(totals, fmt.Sprintf("%d %s", counts[state], state))
+		}
+	}
+	return strings.Join(totals, ", ")
+}
+
+// WriteRootCauses writes each of the root causes to the given writer, along
+// with the reason it is not satisfied and the deps it blocks. Nothing is
+// written if there are no root causes.
+func (s *Summary) WriteRootCauses(w io.Writer) error {
+	if len(s.RootCauses) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Root causes:\n")
+	for _, cause := range s.RootCauses {
+		fmt.Fprintf(&b, "  %s (%s)", cause.Dep.Name, cause.Dep.State)
+		if cause.Dep.Err != nil {
+			fmt.Fprintf(&b, ": %s", cause.Dep.Err)
+		}
+		b.WriteString("\n")
+		if len(cause.Blocks) > 0 {
+			fmt.Fprintf(&b, "    blocks %s\n", strings.Join(cause.Blocks, ", "))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	baz := NewDependency("baz")
+	baz.State = Failed
+	baz.Err = errors.New("oh noes")
+	quux := NewDependency("quux")
+	quux.State = TimedOut
+	qux := NewDependency("qux")
+	qux.State = Satisfied
+
+	foo := NewDependency("foo")
+	foo.Dependencies = []*Dependency{baz}
+	foo.State = Blocked
+	bar := NewDependency("bar")
+	bar.Dependencies = []*Dependency{baz, quux}
+	bar.State = Blocked
+
+	all := NewDependency("all")
+	all.Dependencies = []*Dependency{foo, bar, qux}
+	all.State = Blocked
+
+	s, err := Summarize(all)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	want := "1 satisfied, 1 failed, 1 timed out, 3 blocked"
+	if s.Totals() != want {
+		t.Errorf("wanted totals %q; got %q", want, s.Totals())
+	}
+
+	var causes []string
+	blocks := make(map[string][]string)
+	for _, cause := range s.RootCauses {
+		causes = append(causes, cause.Dep.Name)
+		blocks[cause.Dep.Name] = cause.Blocks
+	}
+	if !reflect.DeepEqual(causes, []string{"baz", "quux"}) {
+		t.Errorf("wanted root causes [baz quux]; got %v", causes)
+	}
+	if !reflect.DeepEqual(blocks["baz"], []string{"foo", "bar", "all"}) {
+		t.Errorf("wanted baz to block [foo bar all]; got %v", blocks["baz"])
+	}
+	if !reflect.DeepEqual(blocks["quux"], []string{"bar", "all"}) {
+		t.Errorf("wanted quux to block [bar all]; got %v", blocks["quux"])
+	}
+
+	buf := new(bytes.Buffer)
+	if err := s.WriteRootCauses(buf); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	wantCauses := "Root causes:\n" +
+		"  baz (failed): oh noes\n" +
+		"    blocks foo, bar, all\n" +
+		"  quux (timed out)\n" +
+		"    blocks bar, all\n"
+	if buf.String() != wantCauses {
+		t.Errorf("wanted:\n%s\ngot:\n%s", wantCauses, buf.String())
+	}
+}
+
+func TestSummary_WriteRootCauses_None(t *testing.T) {
+	dep := NewDependency("foo")
+	dep.State = Satisfied
+
+	s, err := Summarize(dep)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := s.WriteRootCauses(buf); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wanted nothing written; got %q", buf.String())
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package graph
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize is the window size returned by the TIOCGWINSZ ioctl.
+type winsize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+// TerminalWidth returns the width, in columns, of the terminal the given file
+// refers to. False is returned if the file is not a terminal.
+func TerminalWidth(f *os.File) (int, bool) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0, false
+	}
+	return int(ws.cols), true
+}
@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package graph
+
+import (
+	"os"
+)
+
+// TerminalWidth always returns false, as terminals are not detected on
+// Windows.
+func TerminalWidth(f *os.File) (int, bool) {
+	return 0, false
+}
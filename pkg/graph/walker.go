@@ -2,21 +2,25 @@ package graph
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 )
 
 // Walker walks a DependencyGraph, visiting the deps in a given order.
 type Walker interface {
 
-	// Walk traverses the given DependencyGraph from the start node.
-	Walk(graph *DependencyGraph, startNode string) error
+	// Walk traverses the given DependencyGraph from each of the given start
+	// nodes, in order. A dep reachable from more than one start node is only
+	// visited once.
+	Walk(graph *DependencyGraph, startNodes ...string) error
 }
 
 // NewWalker returns a Walker that will traverse the graph using a depth-first,
 // post-order traversal.
 func NewWalker(visitors ...DepVisitor) Walker {
 	return &depthFirstWalker{
-		visitors:   visitors,
-		visitCache: make(map[string]int),
+		visitors: visitors,
+		visited:  make(map[string]bool),
 	}
 }
 
@@ -25,24 +29,37 @@ type depthFirstWalker struct {
 	// visitors are the actions to take on each dep visited in the traversal
 	visitors []DepVisitor
 
-	// visitCache maintains a mapping of the nodes visited
-	visitCache map[string]int
+	// visited is the set of names of the nodes that have been visited
+	visited map[string]bool
+
+	// path is the names of the nodes currently being visited, from the start
+	// node to the current node
+	path []string
 }
 
-// Walk starts a depth-first post-order traversal of the graph, starting at
-// the Dependency with the given name.
-func (w *depthFirstWalker) Walk(graph *DependencyGraph, startNode string) error {
-	start := graph.Get(startNode)
-	if start == nil {
-		return fmt.Errorf("node %s not found", startNode)
+// Walk starts a depth-first post-order traversal of the graph from each of
+// the Dependencies with the given names, in order. A start node that has
+// already been visited from a previous start node is skipped.
+func (w *depthFirstWalker) Walk(graph *DependencyGraph, startNodes ...string) error {
+	starts, err := getNodes(graph, startNodes)
+	if err != nil {
+		return err
+	}
+
+	for _, start := range starts {
+		if w.visited[start.Name] {
+			continue
+		}
+		if err := w.visit(graph, start); err != nil {
+			return err
+		}
 	}
-	return w.visit(graph, start)
+	return nil
 }
 
 // visit uses a nodeVisitor to attempt to visit the given Dependency,
 // recursively visiting the Dependency's own dependencies by calling visit on
 // them.
-// TODO(nickt): detect cycles
 func (w *depthFirstWalker) visit(graph *DependencyGraph, dep *Dependency) error {
 	for _, v := range w.visitors {
 		v.PreVisit(dep)
@@ -53,20 +70,19 @@ func (w *depthFirstWalker) visit(graph *DependencyGraph, dep *Dependency) error
 		return fmt.Errorf("dependency not found in graph")
 	}
 
-	// if we've visited this node, no need to visit again
-	count, visited := w.visitCache[dep.Name]
-	if visited {
-		return nil
+	// if we're already visiting this node higher up in the graph, there is a
+	// cycle
+	if cycle := cyclePath(w.path, dep.Name); cycle != "" {
+		return fmt.Errorf("detected cycle: %s", cycle)
 	}
 
-	// however, if the count on this node is greater than zero, we're already visiting
-	// this node higher up in the graph
-	if count > 0 {
-		return fmt.Errorf("detected cycle")
+	// if we've visited this node, no need to visit again
+	if w.visited[dep.Name] {
+		return nil
 	}
 
-	// and add this to the list of visited nodes
-	w.visitCache[dep.Name]++
+	// add this to the path of nodes being visited
+	w.path = append(w.path, dep.Name)
 
 	// and visit all of the dependent nodes
 	for _, d := range dep.Dependencies {
@@ -84,8 +100,9 @@ func (w *depthFirstWalker) visit(graph *DependencyGraph, dep *Dependency) error
 		}
 	}
 
-	// "un-visit" this dep from the list of nodes
-	w.visitCache[dep.Name]--
+	// remove this dep from the path, and mark it as visited
+	w.path = w.path[:len(w.path)-1]
+	w.visited[dep.Name] = true
 
 	return nil
 }
@@ -95,3 +112,205 @@ func (w *depthFirstWalker) postVisit(dep *Dependency) {
 		v.PostVisit(dep)
 	}
 }
+
+// NewConcurrentWalker returns a Walker that visits up to the given number of
+// Dependencies at a time. A Dependency is only visited once all of its own
+// dependencies have been visited, hence independent deps are visited in
+// parallel, while the ordering guarantees of a post-order traversal are
+// retained for any given path through the graph.
+//
+// The Visit method of each visitor may be called concurrently, and must be
+// safe to do so. PreVisit and PostVisit are never called concurrently.
+func NewConcurrentWalker(jobs int, visitors ...DepVisitor) Walker {
+	if jobs < 1 {
+		jobs = 1
+	}
+	return &concurrentWalker{
+		jobs:     jobs,
+		visitors: visitors,
+	}
+}
+
+type concurrentWalker struct {
+
+	// jobs is the maximum number of deps that will be visited at once
+	jobs int
+
+	// visitors are the actions to take on each dep visited in the traversal
+	visitors []DepVisitor
+
+	// hookMu serializes calls to the PreVisit and PostVisit hooks
+	hookMu sync.Mutex
+}
+
+// visitResult is the outcome of visiting a single Dependency.
+type visitResult struct {
+	dep *Dependency
+	err error
+}
+
+// Walk visits all Dependencies reachable from the Dependencies with the given
+// names. Deps are scheduled as soon as all of their own dependencies have been
+// visited. If visiting a dep returns an error, no further deps are scheduled,
+// and the first error observed is returned once all in-flight deps have
+// completed.
+func (w *concurrentWalker) Walk(graph *DependencyGraph, startNodes ...string) error {
+	starts, err := getNodes(graph, startNodes)
+	if err != nil {
+		return err
+	}
+
+	order, err := postOrder(starts...)
+	if err != nil {
+		return err
+	}
+
+	// track the number of outstanding dependencies of each dep, along with
+	// the deps waiting on each dep
+	pending := make(map[*Dependency]int)
+	dependents := make(map[*Dependency][]*Dependency)
+	var ready []*Dependency
+	for _, dep := range order {
+		seen := make(map[*Dependency]bool)
+		for _, d := range dep.Dependencies {
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			pending[dep]++
+			dependents[d] = append(dependents[d], dep)
+		}
+		if pending[dep] == 0 {
+			ready = append(ready, dep)
+		}
+	}
+
+	results := make(chan visitResult)
+	var running int
+	var firstErr error
+	for {
+		// schedule as many ready deps as we are permitted, unless an error
+		// has already been observed
+		for firstErr == nil && len(ready) > 0 && running < w.jobs {
+			dep := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- visitResult{dep, w.visit(dep)}
+			}()
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			continue
+		}
+
+		for _, d := range dependents[result.dep] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// visit runs each of the visitors on the given Dependency.
+func (w *concurrentWalker) visit(dep *Dependency) error {
+	w.hookMu.Lock()
+	for _, v := range w.visitors {
+		v.PreVisit(dep)
+	}
+	w.hookMu.Unlock()
+
+	defer func() {
+		w.hookMu.Lock()
+		for _, v := range w.visitors {
+			v.PostVisit(dep)
+		}
+		w.hookMu.Unlock()
+	}()
+
+	for _, v := range w.visitors {
+		if err := v.Visit(dep); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getNodes returns the Dependencies with the given names from the graph. An
+// error is returned if any are not found.
+func getNodes(graph *DependencyGraph, names []string) ([]*Dependency, error) {
+	var nodes []*Dependency
+	for _, name := range names {
+		node := graph.Get(name)
+		if node == nil {
+			return nil, fmt.Errorf("node %s not found", name)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// postOrder returns the Dependencies reachable from the given Dependencies,
+// ordered such that each dep appears after all of its own dependencies. An
+// error is returned if the graph contains a cycle.
+func postOrder(starts ...*Dependency) ([]*Dependency, error) {
+	var order []*Dependency
+	visited := make(map[*Dependency]bool)
+	var path []string
+
+	var visit func(dep *Dependency) error
+	visit = func(dep *Dependency) error {
+		if cycle := cyclePath(path, dep.Name); cycle != "" {
+			return fmt.Errorf("detected cycle: %s", cycle)
+		}
+		if visited[dep] {
+			return nil
+		}
+
+		path = append(path, dep.Name)
+		for _, d := range dep.Dependencies {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+
+		visited[dep] = true
+		order = append(order, dep)
+		return nil
+	}
+
+	for _, start := range starts {
+		if err := visit(start); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// cyclePath returns a description of the cycle formed by adding the given
+// name to the end of the path, e.g. "foo -> bar -> foo". An empty string is
+// returned if the name is not already on the path.
+func cyclePath(path []string, name string) string {
+	for i, n := range path {
+		if n == name {
+			cycle := append(append([]string{}, path[i:]...), name)
+			return strings.Join(cycle, " -> ")
+		}
+	}
+	return ""
+}
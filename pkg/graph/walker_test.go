@@ -1,7 +1,11 @@
 package graph
 
 import (
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/nicktrav/matryoshka/pkg/lang"
 )
@@ -22,6 +26,23 @@ func newGraph() *DependencyGraph {
 	return graph
 }
 
+// newCyclicGraph returns a new Dependency graph containing the cycle
+// foo -> bar -> baz -> foo.
+func newCyclicGraph() *DependencyGraph {
+	foo := NewDependency("foo")
+	bar := NewDependency("bar")
+	baz := NewDependency("baz")
+	foo.Dependencies = []*Dependency{bar}
+	bar.Dependencies = []*Dependency{baz}
+	baz.Dependencies = []*Dependency{foo}
+
+	graph := NewDependencyGraph()
+	for _, dep := range []*Dependency{foo, bar, baz} {
+		graph.depMap[dep.Name] = dep
+	}
+	return graph
+}
+
 func TestDepthFirstWalker_Walk(t *testing.T) {
 	graph := newGraph()
 
@@ -49,9 +70,236 @@ func TestDepthFirstWalker_Walk(t *testing.T) {
 	}
 }
 
+func TestDepthFirstWalker_Walk_MultipleRoots(t *testing.T) {
+	graph := newGraph()
+
+	tracker := newTracker()
+	walker := NewWalker(tracker)
+
+	// boom is reachable from both roots, and bam has been visited by the
+	// time it is reached as a root
+	err := walker.Walk(graph, "bar", "bam", "boom")
+	if err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	var got []string
+	for _, dep := range tracker.depsVisited {
+		got = append(got, dep.Name)
+	}
+
+	want := []string{"baz", "boom", "bar", "bam"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("wanted walk %v; got %v", want, got)
+	}
+}
+
+func TestDepthFirstWalker_Walk_NodeNotFound(t *testing.T) {
+	tracker := newTracker()
+	walker := NewWalker(tracker)
+
+	if err := walker.Walk(newGraph(), "bar", "missing"); err == nil {
+		t.Fatal("wanted error; got none")
+	}
+
+	// no deps are visited unless all roots are found
+	if len(tracker.depsVisited) != 0 {
+		t.Errorf("wanted no deps visited; got %+v", tracker.depsVisited)
+	}
+}
+
+func TestDepthFirstWalker_Walk_Cycle(t *testing.T) {
+	graph := newCyclicGraph()
+
+	walker := NewWalker(newTracker())
+	err := walker.Walk(graph, "foo")
+	if err == nil {
+		t.Fatal("wanted error; got none")
+	}
+
+	want := "detected cycle: foo -> bar -> baz -> foo"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("wanted error to contain '%s'; got %s", want, err)
+	}
+}
+
+func TestConcurrentWalker_Walk_Cycle(t *testing.T) {
+	graph := newCyclicGraph()
+
+	tracker := newTracker()
+	walker := NewConcurrentWalker(2, tracker)
+	err := walker.Walk(graph, "foo")
+	if err == nil {
+		t.Fatal("wanted error; got none")
+	}
+
+	want := "detected cycle: foo -> bar -> baz -> foo"
+	if err.Error() != want {
+		t.Errorf("wanted error '%s'; got %s", want, err)
+	}
+
+	if len(tracker.depsVisited) != 0 {
+		t.Errorf("wanted no deps visited; got %+v", tracker.depsVisited)
+	}
+}
+
+func TestConcurrentWalker_Walk(t *testing.T) {
+	graph := newGraph()
+
+	tracker := newTracker()
+	walker := NewConcurrentWalker(4, tracker)
+
+	err := walker.Walk(graph, "foo")
+	if err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	walk := tracker.depsVisited
+	if len(walk) != 5 {
+		t.Fatalf("wanted size of walk to be 5; got %d", len(walk))
+	}
+
+	// each dep must be visited after all of its own deps
+	position := make(map[string]int)
+	for i, dep := range walk {
+		position[dep.Name] = i
+	}
+	for _, dep := range walk {
+		for _, d := range dep.Dependencies {
+			if position[d.Name] > position[dep.Name] {
+				t.Errorf("wanted %s visited before %s; got %+v", d.Name, dep.Name, walk)
+			}
+		}
+	}
+}
+
+func TestConcurrentWalker_Walk_MultipleRoots(t *testing.T) {
+	graph := newGraph()
+
+	tracker := newTracker()
+	walker := NewConcurrentWalker(4, tracker)
+
+	err := walker.Walk(graph, "bar", "bam")
+	if err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	// each dep reachable from either root is visited once
+	visited := make(map[string]int)
+	for _, dep := range tracker.depsVisited {
+		visited[dep.Name]++
+	}
+	for _, name := range []string{"bar", "baz", "boom", "bam"} {
+		if visited[name] != 1 {
+			t.Errorf("wanted %s visited once; visited %d times", name, visited[name])
+		}
+	}
+	if visited["foo"] != 0 {
+		t.Errorf("wanted foo not visited")
+	}
+}
+
+func TestConcurrentWalker_Walk_NodeNotFound(t *testing.T) {
+	walker := NewConcurrentWalker(2, newTracker())
+
+	err := walker.Walk(newGraph(), "missing")
+	if err == nil {
+		t.Fatal("wanted error; got none")
+	}
+}
+
+func TestConcurrentWalker_Walk_RunsIndependentDepsConcurrently(t *testing.T) {
+	graph := newGraph()
+
+	// baz and boom have no deps, and may be visited at the same time
+	v := &blockingVisitor{release: make(chan struct{})}
+	walker := NewConcurrentWalker(2, v)
+
+	done := make(chan error)
+	go func() {
+		done <- walker.Walk(graph, "foo")
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for v.current() < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("wanted 2 deps visited concurrently; got %d", v.current())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	close(v.release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("got error: %+v", err)
+	}
+
+	if v.max > 2 {
+		t.Errorf("wanted at most 2 deps visited concurrently; got %d", v.max)
+	}
+}
+
+func TestConcurrentWalker_Walk_VisitError(t *testing.T) {
+	graph := newGraph()
+
+	e := errors.New("oh noes")
+	tracker := newTracker()
+	walker := NewConcurrentWalker(1, &failingVisitor{err: e}, tracker)
+
+	err := walker.Walk(graph, "foo")
+	if err != e {
+		t.Fatalf("wanted error %+v; got %+v", e, err)
+	}
+
+	// no dep should be visited after the first failure
+	if len(tracker.depsVisited) != 0 {
+		t.Errorf("wanted no deps visited; got %+v", tracker.depsVisited)
+	}
+}
+
+// blockingVisitor is a DepVisitor that blocks in Visit until released,
+// recording the number of deps being visited at once.
+type blockingVisitor struct {
+	mu      sync.Mutex
+	running int
+	max     int
+	release chan struct{}
+}
+
+func (v *blockingVisitor) current() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.running
+}
+
+func (v *blockingVisitor) Visit(dep *Dependency) error {
+	v.mu.Lock()
+	v.running++
+	if v.running > v.max {
+		v.max = v.running
+	}
+	v.mu.Unlock()
+
+	<-v.release
+
+	v.mu.Lock()
+	v.running--
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *blockingVisitor) PreVisit(dep *Dependency) {
+}
+
+func (v *blockingVisitor) PostVisit(dep *Dependency) {
+}
+
 // pathTracker is a DepVisitor that maintains an ordered list of Deps visited.
 type pathTracker struct {
 
+	// mu guards depsVisited, as deps may be visited concurrently
+	mu sync.Mutex
+
 	// depsVisited is a slice containing pointers to Deps visited by the
 	// walker.
 	depsVisited []*Dependency
@@ -64,6 +312,8 @@ func newTracker() *pathTracker {
 
 // Visit adds the dep to the deps visited
 func (t *pathTracker) Visit(dep *Dependency) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.depsVisited = append(t.depsVisited, dep)
 	return nil
 }
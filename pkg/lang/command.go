@@ -0,0 +1,64 @@
+package lang
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// Command is an action declared in the met or meet list of a dep. Each of the
+// builtins that declare an action, e.g. `shell()` or `file()`, returns a
+// Command.
+type Command interface {
+	starlark.Value
+
+	// command restricts the implementations of Command to this package.
+	command()
+}
+
+// Resource is a Command describing some state on the local system, such as a
+// file or a directory. A Resource knows how to both check whether the state
+// is present, and how to create it, so a single Resource can be used in both
+// the met and meet lists of a dep.
+type Resource interface {
+	Command
+
+	// resource restricts the implementations of Resource to this package.
+	resource()
+}
+
+// asCommand returns the given value as a Command. An error is returned if the
+// value is not a Command.
+func asCommand(value starlark.Value) (Command, error) {
+	switch v := value.(type) {
+	case ShellCmd:
+		return &v, nil
+	case Command:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("list item %+v is not a command", value)
+	}
+}
+
+// resolvePath returns the given path, resolved relative to the directory of
+// the module from which the builtin running on the given thread was called.
+// Absolute paths, and paths relative to the home directory (i.e. starting with
+// "~"), are returned unchanged.
+func resolvePath(t *starlark.Thread, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+
+	if filepath.IsAbs(path) || path == "~" || strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	dir := "."
+	if pos := callerPos(t); pos.Line > 0 {
+		dir = filepath.Dir(pos.Filename())
+	}
+
+	return filepath.Abs(filepath.Join(dir, path))
+}
@@ -0,0 +1,67 @@
+package lang
+
+import (
+	"fmt"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Edge is a directed edge between two named nodes in a graph, along with the
+// position in a .dep file at which the edge was declared.
+type Edge struct {
+
+	// From is the name of the node the edge starts at.
+	From string
+
+	// To is the name of the node the edge ends at.
+	To string
+
+	// Pos is the position at which the edge was declared.
+	Pos syntax.Position
+}
+
+// CycleError is returned when a cycle is detected in a graph, either in the
+// graph of modules loaded via load(), or in the graph of deps.
+type CycleError struct {
+
+	// Graph is a short description of the graph containing the cycle, e.g.
+	// "load" or "dep".
+	Graph string
+
+	// Edges are the edges forming the cycle, in order. The last edge ends at
+	// the node the first edge starts at.
+	Edges []Edge
+}
+
+// Error returns the full cycle, followed by the position at which each edge
+// of the cycle was declared.
+func (e *CycleError) Error() string {
+	if len(e.Edges) == 0 {
+		return fmt.Sprintf("cycle in %s graph", e.Graph)
+	}
+
+	var names []string
+	for _, edge := range e.Edges {
+		names = append(names, edge.From)
+	}
+	names = append(names, e.Edges[len(e.Edges)-1].To)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "cycle in %s graph: %s", e.Graph, strings.Join(names, " -> "))
+	for _, edge := range e.Edges {
+		fmt.Fprintf(&b, "\n  %s: %s -> %s", edge.Pos, edge.From, edge.To)
+	}
+	return b.String()
+}
+
+// callerPos returns the position in the Starlark program from which the
+// builtin currently running on the given thread was called. The zero
+// Position is returned if the builtin was not called from Starlark.
+func callerPos(t *starlark.Thread) syntax.Position {
+	if t == nil || t.CallStackDepth() < 2 {
+		return syntax.Position{}
+	}
+	return t.CallFrame(1).Pos
+}
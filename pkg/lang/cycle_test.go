@@ -0,0 +1,43 @@
+package lang
+
+import (
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestCycleError_Error(t *testing.T) {
+	err := &CycleError{
+		Graph: "dep",
+		Edges: []Edge{
+			{From: "all", To: "baz"},
+			{From: "baz", To: "foo"},
+			{From: "foo", To: "all"},
+		},
+	}
+
+	want := "cycle in dep graph: all -> baz -> foo -> all\n" +
+		"  <invalid>: all -> baz\n" +
+		"  <invalid>: baz -> foo\n" +
+		"  <invalid>: foo -> all"
+	if err.Error() != want {
+		t.Errorf("wanted '%s'; got '%s'", want, err.Error())
+	}
+}
+
+func TestCycleError_Error_NoEdges(t *testing.T) {
+	err := &CycleError{Graph: "load"}
+
+	want := "cycle in load graph"
+	if err.Error() != want {
+		t.Errorf("wanted '%s'; got '%s'", want, err.Error())
+	}
+}
+
+func TestCallerPos_NoCaller(t *testing.T) {
+	pos := callerPos(&starlark.Thread{})
+
+	if pos.Line != 0 {
+		t.Errorf("wanted zero position; got %s", pos)
+	}
+}
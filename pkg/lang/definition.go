@@ -0,0 +1,81 @@
+package lang
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Definition returns a digest of the definition of the Dep: its name,
+// description, tags, requirements, commands and the options applied to them.
+// The digest changes if any of these change, including the arguments of any
+// command, but not if the dep() call is moved, e.g. to another module.
+//
+// The digest also covers the contents of the files the commands read when
+// they are run, as returned by Sources, so it changes if a template is edited.
+// A source that cannot be read is digested as empty.
+//
+// Requirements are identified by name, so the digest of a Dep does not change
+// when the definition of a requirement changes.
+func (d *Dep) Definition() string {
+	h := sha256.New()
+
+	writeField(h, "name", d.Name)
+	writeField(h, "description", d.Description)
+	writeField(h, "tags", d.Tags)
+	for _, req := range d.Requirements {
+		writeField(h, "requires", struct {
+			Name   string
+			Enable bool
+		}{req.Name, req.Enable})
+	}
+	for _, cmd := range d.MetCommands {
+		writeField(h, "met", cmd)
+	}
+	for _, cmd := range d.MeetCommands {
+		writeField(h, "meet", cmd)
+	}
+	writeField(h, "enable", d.Enable)
+	writeField(h, "retry", d.Retry)
+	writeField(h, "env", d.Env)
+	writeField(h, "privileged", d.Privileged)
+	for _, source := range Sources(d) {
+		writeField(h, "source", struct {
+			Path   string
+			Digest string
+		}{source, sourceDigest(source)})
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField writes the given field of a definition to the given writer, as
+// its key, followed by the type and JSON encoding of its value, each prefixed
+// with its length. Maps are encoded with their keys sorted, so the encoding of
+// a given value is stable.
+func writeField(w io.Writer, key string, value interface{}) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		// the values of a definition are all plain data, but fall back to
+		// their Go syntax representation rather than dropping them
+		encoded = []byte(fmt.Sprintf("%#v", value))
+	}
+
+	typ := fmt.Sprintf("%T", value)
+	fmt.Fprintf(w, "%d:%s%d:%s%d:", len(key), key, len(typ), typ, len(encoded))
+	_, _ = w.Write(encoded)
+}
+
+// sourceDigest returns a digest of the contents of the source with the given
+// path, or an empty string if it cannot be read.
+func sourceDigest(path string) string {
+	src, err := readSource(path)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(src)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
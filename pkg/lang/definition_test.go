@@ -0,0 +1,92 @@
+package lang
+
+import (
+	"io/ioutil"
+	oslib "os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.starlark.net/syntax"
+)
+
+func TestDep_Definition(t *testing.T) {
+	content := "old"
+	newDep := func() *Dep {
+		return &Dep{
+			Name:         "foo",
+			Requirements: []*Dep{{Name: "bar", Enable: true}},
+			MetCommands:  []Command{&File{Path: "/foo", Content: &content}},
+			Enable:       true,
+			Env:          map[string]string{"A": "1", "B": "2"},
+		}
+	}
+
+	want := newDep().Definition()
+	if !strings.HasPrefix(want, "sha256:") {
+		t.Errorf("wanted a sha256 digest; got %s", want)
+	}
+
+	// the position of the dep() call is not part of the definition
+	moved := newDep()
+	moved.Pos = syntax.MakePosition(&content, 3, 1)
+	if got := moved.Definition(); got != want {
+		t.Errorf("wanted digest %s for a moved dep; got %s", want, got)
+	}
+
+	changes := map[string]func(d *Dep){
+		"name":        func(d *Dep) { d.Name = "baz" },
+		"description": func(d *Dep) { d.Description = "a dep" },
+		"requirement": func(d *Dep) { d.Requirements[0] = &Dep{Name: "qux", Enable: true} },
+		"content": func(d *Dep) {
+			changed := "new"
+			d.MetCommands = []Command{&File{Path: "/foo", Content: &changed}}
+		},
+		"command type": func(d *Dep) { d.MetCommands = []Command{&Directory{Path: "/foo"}} },
+		"env":          func(d *Dep) { d.Env["B"] = "3" },
+		"retry":        func(d *Dep) { d.Retry = &Retry{Attempts: 3} },
+		"privileged":   func(d *Dep) { d.Privileged = true },
+	}
+	for name, change := range changes {
+		dep := newDep()
+		change(dep)
+		if got := dep.Definition(); got == want {
+			t.Errorf("wanted digest to change with the %s", name)
+		}
+	}
+}
+
+func TestDep_Definition_Sources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "definition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oslib.RemoveAll(dir)
+
+	src := filepath.Join(dir, "foo.tmpl")
+	dep := &Dep{
+		Name:        "foo",
+		MetCommands: []Command{&Template{Src: src, Dst: filepath.Join(dir, "foo")}},
+	}
+
+	// a missing source is digested as empty, rather than failing
+	missing := dep.Definition()
+
+	write := func(content string) string {
+		if err := ioutil.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dep.Definition()
+	}
+
+	before := write("{{ .foo }}\n")
+	if before == missing {
+		t.Errorf("wanted digest to change once the template exists")
+	}
+	if after := write("{{ .foo }}\n"); after != before {
+		t.Errorf("wanted digest %s for an unchanged template; got %s", before, after)
+	}
+	if after := write("{{ .bar }}\n"); after == before {
+		t.Errorf("wanted digest to change with the template")
+	}
+}
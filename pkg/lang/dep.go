@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
 )
 
 const (
@@ -13,6 +14,10 @@ const (
 	argMet         = starlark.String("met")
 	argMeet        = starlark.String("meet")
 	argEnable      = starlark.String("enable")
+	argRetry       = starlark.String("retry")
+	argEnv         = starlark.String("env")
+	argPrivileged  = starlark.String("privileged")
+	argTags        = starlark.String("tags")
 )
 
 // Dep represents the `dep()` builtin function and models a dependency in the
@@ -26,7 +31,7 @@ const (
 //
 //     description = 'A meaningful description of the dependency',
 //
-//     requirements = [
+//     requires = [
 //       // a list of other dep variables, either in the current module,
 //       // or contained within another module
 //       bar, baz,
@@ -36,11 +41,14 @@ const (
 //       // a list of actions that must all be satisfied for the dep to be
 //       // satisfied
 //       shell("true"),
+//       symlink("dotfiles/vimrc", "~/.vimrc"),
 //     ],
 //
 //     meet = [
 //       // a list of actions that will be run to attempt to satisfy the
-//       // current dependency
+//       // current dependency. If omitted, the resources (e.g. file(),
+//       // symlink()) in the met list are used, as each resource knows how
+//       // to create the state it checks for
 //       shell("true"),
 //     ],
 //
@@ -48,6 +56,28 @@ const (
 //     whether this dep should be considered in the dependency graph, e.g.
 //     only consider the current Dep on Linux
 //     enable = os() == 'linux'
+//
+//     retry takes a retry policy, determining how many times the meet
+//     actions are attempted if they fail to satisfy the dependency
+//     retry = retry(attempts=3, backoff='5s'),
+//
+//     env takes a dict of variables added to the environment of each of the
+//     shell commands and git() resources in the met and meet lists.
+//     Variables set on a shell command itself take precedence. Packages are
+//     installed with the environment of the current process, and the other
+//     resources do not run any process
+//     env = {'GOPATH': '~/go'},
+//
+//     privileged takes a Boolean, determining whether each of the shell
+//     commands in the met and meet lists runs as root via sudo, along with
+//     the git commands that change a git() clone. Packages are installed via
+//     sudo regardless. A privileged dep may not have any of the file
+//     resources, e.g. file() or template(), as they cannot run as root
+//     privileged = True,
+//
+//     tags takes a list of strings, allowing deps to be selected by tag,
+//     along with their requirements, e.g. `apply --tag editor`
+//     tags = ['editor', 'lang-go'],
 //   )
 //
 type Dep struct {
@@ -64,20 +94,43 @@ type Dep struct {
 	// depends on.
 	Requirements []*Dep
 
-	// MetCommand is a list of ShellCmds that should be run, in order, to
+	// Tags are the tags used to select the dependency, along with its
+	// requirements, when applying a subset of the dependencies.
+	Tags []string
+
+	// MetCommand is a list of Commands that should be run, in order, to
 	// determine whether this dependency is satisfied. These commands should be
 	// lightweight and ideally do not have side-effects. For example, these
 	// commands could check for the presence of a binary or directory.
-	MetCommands []*ShellCmd
+	MetCommands []Command
 
-	// MeetCommands is a list of ShellCmds that should be run, in order, to
+	// MeetCommands is a list of Commands that should be run, in order, to
 	// attempt to satisfy this dependency. These commands typically have
 	// side-effects and  will install a particular dependency, clone a repo,
 	// or create a directory.
-	MeetCommands []*ShellCmd
+	//
+	// If no meet list is given, the MeetCommands are the Resources in the
+	// MetCommands, as a Resource knows how to create the state it checks.
+	MeetCommands []Command
 
 	// Enabled determines whether the current Dep is enabled.
 	Enable bool
+
+	// Retry is the policy for retrying the MeetCommands, or nil if the
+	// MeetCommands should only be attempted once.
+	Retry *Retry
+
+	// Env contains variables added to the environment of each of the shell
+	// commands and git resources in MetCommands and MeetCommands.
+	Env map[string]string
+
+	// Privileged determines whether each of the shell commands in
+	// MetCommands and MeetCommands runs as root via sudo, along with the git
+	// commands that change the clone of each Git.
+	Privileged bool
+
+	// Pos is the position of the dep() call that declared the dependency.
+	Pos syntax.Position
 }
 
 // String returns the string representation of the Dep.
@@ -105,7 +158,23 @@ func (d Dep) Hash() (uint32, error) {
 // FnDep transforms the arguments into a Dep object after performing
 // validation on the keyword arguments.
 func FnDep(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-	dep := &Dep{Enable: true}
+	dep, err := newDep(t, args, kwargs)
+	if err != nil {
+		return nil, positionError(callerPos(t), fmt.Errorf("dep: %w", err))
+	}
+	return dep, nil
+}
+
+// newDep returns a Dep from the arguments to the `dep` function, returning an
+// error if the arguments are invalid.
+func newDep(t *starlark.Thread, args starlark.Tuple, kwargs []starlark.Tuple) (*Dep, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("unexpected positional arguments; all arguments must be named")
+	}
+
+	dep := &Dep{Enable: true, Pos: callerPos(t)}
+	nameGiven := false
+	meetGiven := false
 
 	for _, tuple := range kwargs {
 		key := tuple.Index(0)
@@ -119,6 +188,7 @@ func FnDep(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs
 				return nil, err
 			}
 			dep.Name = name
+			nameGiven = true
 
 		case argDescription:
 			description, err := asString(value)
@@ -147,6 +217,7 @@ func FnDep(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs
 				return nil, err
 			}
 			dep.MeetCommands = cmds
+			meetGiven = true
 
 		case argEnable:
 			enable, err := asBool(value)
@@ -155,16 +226,84 @@ func FnDep(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs
 			}
 			dep.Enable = enable
 
+		case argRetry:
+			retry, ok := value.(Retry)
+			if !ok {
+				return nil, fmt.Errorf("value %v is not a retry policy", value)
+			}
+			dep.Retry = &retry
+
+		case argEnv:
+			env, err := asEnv(value)
+			if err != nil {
+				return nil, fmt.Errorf("argument to env: %s", err)
+			}
+			dep.Env = env
+
+		case argPrivileged:
+			privileged, err := asBool(value)
+			if err != nil {
+				return nil, err
+			}
+			dep.Privileged = privileged
+
+		case argTags:
+			tags, err := asTags(value)
+			if err != nil {
+				return nil, fmt.Errorf("argument to tags: %s", err)
+			}
+			dep.Tags = tags
+
 		default:
-			continue
+			if key == starlark.String("requirements") {
+				return nil, fmt.Errorf("unexpected keyword argument %s (did you mean %s?)", key, argRequires)
+			}
+			return nil, fmt.Errorf("unexpected keyword argument %s", key)
 		}
 	}
 
-	// TODO(nickt): Validation of arguments
+	if !nameGiven {
+		return nil, fmt.Errorf("missing name")
+	}
+
+	if err := checkName(dep.Name); err != nil {
+		return nil, err
+	}
+
+	if err := checkPrivileged(dep); err != nil {
+		return nil, err
+	}
+
+	// resources in the met list converge themselves, unless told otherwise
+	if !meetGiven {
+		for _, cmd := range dep.MetCommands {
+			if resource, ok := cmd.(Resource); ok {
+				dep.MeetCommands = append(dep.MeetCommands, resource)
+			}
+		}
+	}
 
 	return dep, nil
 }
 
+// checkPrivileged returns an error if the dep is privileged, but has a command
+// that cannot run as root, i.e. a resource that reads and writes files as the
+// current user.
+func checkPrivileged(dep *Dep) error {
+	if !dep.Privileged {
+		return nil
+	}
+
+	for _, cmd := range append(append([]Command{}, dep.MetCommands...), dep.MeetCommands...) {
+		switch cmd.(type) {
+		case *ShellCmd, *Git, *Package:
+		default:
+			return fmt.Errorf("privileged: %s cannot run as root; use a shell command instead", cmd.Type())
+		}
+	}
+	return nil
+}
+
 // asString returns the given starlark.Value as a Go string.
 // An error is returned if the value is not a string.
 func asString(value starlark.Value) (string, error) {
@@ -200,26 +339,54 @@ func asRequirementsList(value starlark.Value) ([]*Dep, error) {
 	return deps, nil
 }
 
-// asCommandList returns the given list value a slice of ShellCmds.
-// An error is returned if the value is not a list of ShellCmds.
-func asCommandList(value starlark.Value) ([]*ShellCmd, error) {
+// asTags returns the given list value as a slice of tags. An error is
+// returned if the value is not a list of valid tags.
+func asTags(value starlark.Value) ([]string, error) {
+	list, ok := value.(*starlark.List)
+	if !ok {
+		return nil, fmt.Errorf("value %v is not a list", value)
+	}
+
+	var tags []string
+
+	iter := list.Iterate()
+	defer iter.Done()
+
+	var v starlark.Value
+	for iter.Next(&v) {
+		tag, err := asString(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkIdentifier("tag", tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// asCommandList returns the given list value a slice of Commands.
+// An error is returned if the value is not a list of Commands.
+func asCommandList(value starlark.Value) ([]Command, error) {
 	list, ok := value.(*starlark.List)
 	if !ok {
 		return nil, fmt.Errorf("value %+v is not a list", value)
 	}
 
-	var commands []*ShellCmd
+	var commands []Command
 
 	iter := list.Iterate()
 	defer iter.Done()
 
 	var v starlark.Value
 	for iter.Next(&v) {
-		command, ok := v.(ShellCmd)
-		if !ok {
-			return nil, fmt.Errorf("list item %+v is not a command", v)
+		command, err := asCommand(v)
+		if err != nil {
+			return nil, err
 		}
-		commands = append(commands, &command)
+		commands = append(commands, command)
 	}
 
 	return commands, nil
@@ -2,6 +2,7 @@ package lang
 
 import (
 	"math/big"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -159,7 +160,7 @@ func TestAsCommandList(t *testing.T) {
 
 	for i, item := range list {
 		want := cmdItems[i].Command
-		got := item.Command
+		got := item.(*ShellCmd).Command
 		if want != got {
 			t.Errorf("want %+v, got %+v", want, got)
 		}
@@ -228,3 +229,96 @@ func TestAsBool(t *testing.T) {
 		}
 	}
 }
+
+func TestFnDep_privileged(t *testing.T) {
+	kwargs := []starlark.Tuple{
+		{starlark.String("name"), starlark.String("foo")},
+		{starlark.String("privileged"), starlark.True},
+	}
+
+	value, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnDep: %s", err)
+	}
+
+	if !value.(*Dep).Privileged {
+		t.Error("wanted dep to be privileged")
+	}
+}
+
+func TestFnDep_privileged_Resources(t *testing.T) {
+	met := starlark.NewList([]starlark.Value{&ShellCmd{Command: "true"}, &Git{URL: "u", Dest: "/opt/src"}})
+	kwargs := []starlark.Tuple{
+		{starlark.String("name"), starlark.String("foo")},
+		{starlark.String("privileged"), starlark.True},
+		{starlark.String("met"), met},
+	}
+	if _, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs); err != nil {
+		t.Fatalf("error running FnDep: %s", err)
+	}
+
+	// a file resource would be read and written as the current user
+	met = starlark.NewList([]starlark.Value{&File{Path: "/etc/foo"}})
+	kwargs[2] = starlark.Tuple{starlark.String("met"), met}
+	_, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+	if err == nil || !strings.Contains(err.Error(), "dep.File cannot run as root") {
+		t.Errorf("wanted error for a privileged file resource; got %v", err)
+	}
+}
+
+func TestFnDep_env(t *testing.T) {
+	env := starlark.NewDict(1)
+	_ = env.SetKey(starlark.String("FOO"), starlark.String("bar"))
+
+	kwargs := []starlark.Tuple{
+		{starlark.String("name"), starlark.String("foo")},
+		{starlark.String("env"), env},
+	}
+
+	value, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnDep: %s", err)
+	}
+
+	if got := value.(*Dep).Env; len(got) != 1 || got["FOO"] != "bar" {
+		t.Errorf("wanted env FOO=bar; got %+v", got)
+	}
+}
+
+func TestFnDep_tags(t *testing.T) {
+	kwargs := []starlark.Tuple{
+		{starlark.String("name"), starlark.String("foo")},
+		{starlark.String("tags"), starlark.NewList([]starlark.Value{starlark.String("editor"), starlark.String("lang-go")})},
+	}
+
+	value, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnDep: %s", err)
+	}
+
+	want := []string{"editor", "lang-go"}
+	if got := value.(*Dep).Tags; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted tags %v; got %v", want, got)
+	}
+}
+
+func TestFnDep_invalidTags(t *testing.T) {
+	testCases := map[string]starlark.Value{
+		"not a list":   starlark.String("editor"),
+		"not a string": starlark.NewList([]starlark.Value{starlark.MakeInt(1)}),
+		"invalid tag":  starlark.NewList([]starlark.Value{starlark.String("lang go")}),
+		"empty tag":    starlark.NewList([]starlark.Value{starlark.String("")}),
+	}
+
+	for name, tags := range testCases {
+		kwargs := []starlark.Tuple{
+			{starlark.String("name"), starlark.String("foo")},
+			{starlark.String("tags"), tags},
+		}
+
+		_, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+		if err == nil || !strings.Contains(err.Error(), "argument to tags") {
+			t.Errorf("%s: wanted error for tags; got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package lang
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	oslib "os"
+	"path/filepath"
+	"sort"
+)
+
+// Digest returns a digest of the sources of the given modules, which are
+// paths within the given root directory, as returned by Parser.Modules, and of
+// the given sources read by the deps when they are run, as returned by
+// Sources. The digest changes if a module is added, removed, renamed or
+// edited, or if a source is created, edited or removed, but not if the root
+// directory itself is moved.
+func Digest(root string, modules []string, sources []string) (string, error) {
+	sorted := append([]string{}, modules...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, module := range sorted {
+		rel, err := filepath.Rel(root, module)
+		if err != nil {
+			return "", fmt.Errorf("digest: %s", err)
+		}
+
+		src, err := ioutil.ReadFile(module)
+		if err != nil {
+			return "", fmt.Errorf("digest: %s", err)
+		}
+
+		// the length of each field is written before it, such that the
+		// boundaries between modules are unambiguous
+		fmt.Fprintf(h, "%d:%s%d:", len(rel), filepath.ToSlash(rel), len(src))
+		h.Write(src)
+	}
+
+	// sources may be anywhere, so are identified by the paths given to the
+	// commands that read them. A source may not exist until it is created
+	// by another dep, and is digested as absent until then
+	for _, source := range sources {
+		src, err := readSource(source)
+		if oslib.IsNotExist(err) {
+			fmt.Fprintf(h, "%d:%s-:", len(source), source)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("digest: %s", err)
+		}
+
+		fmt.Fprintf(h, "%d:%s%d:", len(source), source, len(src))
+		h.Write(src)
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,118 @@
+package lang
+
+import (
+	"io/ioutil"
+	oslib "os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	parser := NewParser(multiFile)
+	if err := parser.Run(); err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	digest, err := Digest(multiFile, parser.Modules(), nil)
+	if err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		t.Errorf("wanted a sha256 digest; got %s", digest)
+	}
+
+	// the digest does not depend on the order of the modules
+	modules := parser.Modules()
+	for i, j := 0, len(modules)-1; i < j; i, j = i+1, j-1 {
+		modules[i], modules[j] = modules[j], modules[i]
+	}
+	reversed, err := Digest(multiFile, modules, nil)
+	if err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if reversed != digest {
+		t.Errorf("wanted digest %s; got %s", digest, reversed)
+	}
+}
+
+func TestDigest_Changes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oslib.RemoveAll(dir)
+
+	mainPath := filepath.Join(dir, main)
+	write := func(src string) string {
+		if err := ioutil.WriteFile(mainPath, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		digest, err := Digest(dir, []string{mainPath}, nil)
+		if err != nil {
+			t.Fatalf("did not expect error %s", err)
+		}
+		return digest
+	}
+
+	before := write("all = dep(name = 'all')\n")
+	if after := write("all = dep(name = 'all')\n"); after != before {
+		t.Errorf("wanted digest of unchanged source to be %s; got %s", before, after)
+	}
+	if after := write("all = dep(name = 'other')\n"); after == before {
+		t.Errorf("wanted digest of changed source to change")
+	}
+
+	if _, err := Digest(dir, []string{filepath.Join(dir, "missing.dep")}, nil); err == nil {
+		t.Errorf("wanted an error for a missing module")
+	}
+}
+
+func TestDigest_Sources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oslib.RemoveAll(dir)
+
+	mainPath := filepath.Join(dir, main)
+	if err := ioutil.WriteFile(mainPath, []byte("all = dep(name = 'all')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srcPath := filepath.Join(dir, "foo.tmpl")
+	write := func(src string) string {
+		if err := ioutil.WriteFile(srcPath, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		digest, err := Digest(dir, []string{mainPath}, []string{srcPath})
+		if err != nil {
+			t.Fatalf("did not expect error %s", err)
+		}
+		return digest
+	}
+
+	before := write("foo\n")
+	if after := write("foo\n"); after != before {
+		t.Errorf("wanted digest of unchanged template to be %s; got %s", before, after)
+	}
+	if after := write("bar\n"); after == before {
+		t.Errorf("wanted digest of changed template to change")
+	}
+
+	// a template that does not exist yet, e.g. as it is created by another
+	// dep, is digested as absent
+	if err := oslib.Remove(srcPath); err != nil {
+		t.Fatal(err)
+	}
+	missing, err := Digest(dir, []string{mainPath}, []string{srcPath})
+	if err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+	if missing == before {
+		t.Errorf("wanted digest of missing template to differ")
+	}
+	if empty := write(""); empty == missing {
+		t.Errorf("wanted digest of empty template to differ from a missing one")
+	}
+}
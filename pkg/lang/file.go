@@ -0,0 +1,286 @@
+package lang
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// File represents the `file()` builtin function and models a regular file
+// that should exist on the local filesystem.
+//
+// The structure of `file` is as follows:
+//
+//   file(
+//     '~/.hushlogin', // the path to the file
+//     content='',     // the content of the file (defaults to any content)
+//     mode=0o644,     // the permissions of the file (defaults to any
+//                     // permissions, or 0o644 if the file is created)
+//   )
+//
+// Relative paths are resolved relative to the directory of the module in
+// which the file is declared.
+type File struct {
+
+	// Path is the path to the file.
+	Path string
+
+	// Content is the expected content of the file, or nil if the file may
+	// have any content.
+	Content *string
+
+	// Mode is the expected permission bits of the file, or zero if the file
+	// may have any permissions.
+	Mode uint32
+}
+
+// String returns the string representation of the File.
+func (f File) String() string {
+	return fmt.Sprintf("<dep.File %q>", f.Path)
+}
+
+// Type returns a short description about File's type.
+func (f File) Type() string { return "dep.File" }
+
+// Freeze does nothing for a File.
+func (f File) Freeze() {}
+
+// Truth always returns true for a File.
+func (f File) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by File.
+func (f File) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", f.Type())
+}
+
+func (f File) command()  {}
+func (f File) resource() {}
+
+// FnFile implements the signature for a builtin function and implements
+// the functionality of the `file` function.
+func FnFile(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	var content starlark.Value
+	var mode int
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path, "content?", &content, "mode?", &mode)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolvePath(t, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fn.Name(), err)
+	}
+
+	if err := checkMode(mode); err != nil {
+		return nil, fmt.Errorf("%s: %s", fn.Name(), err)
+	}
+
+	f := &File{Path: resolved, Mode: uint32(mode)}
+	if content != nil {
+		s, ok := starlark.AsString(content)
+		if !ok {
+			return nil, fmt.Errorf("%s: argument to content is not a string", fn.Name())
+		}
+		f.Content = &s
+	}
+
+	return f, nil
+}
+
+// Symlink represents the `symlink()` builtin function and models a symbolic
+// link that should exist on the local filesystem.
+//
+// The structure of `symlink` is as follows:
+//
+//   symlink(
+//     'dotfiles/vimrc', // the path the link points to
+//     '~/.vimrc',       // the path of the link itself
+//   )
+//
+// Relative paths are resolved relative to the directory of the module in
+// which the link is declared.
+type Symlink struct {
+
+	// Src is the path the link points to.
+	Src string
+
+	// Dst is the path of the link.
+	Dst string
+}
+
+// String returns the string representation of the Symlink.
+func (s Symlink) String() string {
+	return fmt.Sprintf("<dep.Symlink %q -> %q>", s.Dst, s.Src)
+}
+
+// Type returns a short description about Symlink's type.
+func (s Symlink) Type() string { return "dep.Symlink" }
+
+// Freeze does nothing for a Symlink.
+func (s Symlink) Freeze() {}
+
+// Truth always returns true for a Symlink.
+func (s Symlink) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by Symlink.
+func (s Symlink) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", s.Type())
+}
+
+func (s Symlink) command()  {}
+func (s Symlink) resource() {}
+
+// FnSymlink implements the signature for a builtin function and implements
+// the functionality of the `symlink` function.
+func FnSymlink(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var src, dst string
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "src", &src, "dst", &dst)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedSrc, err := resolvePath(t, src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: src: %s", fn.Name(), err)
+	}
+
+	resolvedDst, err := resolvePath(t, dst)
+	if err != nil {
+		return nil, fmt.Errorf("%s: dst: %s", fn.Name(), err)
+	}
+
+	return &Symlink{Src: resolvedSrc, Dst: resolvedDst}, nil
+}
+
+// Directory represents the `directory()` builtin function and models a
+// directory that should exist on the local filesystem.
+//
+// The structure of `directory` is as follows:
+//
+//   directory(
+//     '~/src',    // the path to the directory
+//     mode=0o755, // the permissions of the directory (defaults to any
+//                 // permissions, or 0o755 if the directory is created)
+//   )
+//
+// Relative paths are resolved relative to the directory of the module in
+// which the directory is declared.
+type Directory struct {
+
+	// Path is the path to the directory.
+	Path string
+
+	// Mode is the expected permission bits of the directory, or zero if the
+	// directory may have any permissions.
+	Mode uint32
+}
+
+// String returns the string representation of the Directory.
+func (d Directory) String() string {
+	return fmt.Sprintf("<dep.Directory %q>", d.Path)
+}
+
+// Type returns a short description about Directory's type.
+func (d Directory) Type() string { return "dep.Directory" }
+
+// Freeze does nothing for a Directory.
+func (d Directory) Freeze() {}
+
+// Truth always returns true for a Directory.
+func (d Directory) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by Directory.
+func (d Directory) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", d.Type())
+}
+
+func (d Directory) command()  {}
+func (d Directory) resource() {}
+
+// FnDirectory implements the signature for a builtin function and implements
+// the functionality of the `directory` function.
+func FnDirectory(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	var mode int
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path, "mode?", &mode)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolvePath(t, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fn.Name(), err)
+	}
+
+	if err := checkMode(mode); err != nil {
+		return nil, fmt.Errorf("%s: %s", fn.Name(), err)
+	}
+
+	return &Directory{Path: resolved, Mode: uint32(mode)}, nil
+}
+
+// Absent represents the `absent()` builtin function and models a path that
+// should not exist on the local filesystem.
+//
+// The structure of `absent` is as follows:
+//
+//   absent(
+//     '~/.old_config', // the path that should not exist
+//   )
+//
+// Relative paths are resolved relative to the directory of the module in
+// which the path is declared.
+type Absent struct {
+
+	// Path is the path that should not exist.
+	Path string
+}
+
+// String returns the string representation of the Absent.
+func (a Absent) String() string {
+	return fmt.Sprintf("<dep.Absent %q>", a.Path)
+}
+
+// Type returns a short description about Absent's type.
+func (a Absent) Type() string { return "dep.Absent" }
+
+// Freeze does nothing for an Absent.
+func (a Absent) Freeze() {}
+
+// Truth always returns true for an Absent.
+func (a Absent) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by Absent.
+func (a Absent) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", a.Type())
+}
+
+func (a Absent) command()  {}
+func (a Absent) resource() {}
+
+// FnAbsent implements the signature for a builtin function and implements
+// the functionality of the `absent` function.
+func FnAbsent(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolvePath(t, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fn.Name(), err)
+	}
+
+	return &Absent{Path: resolved}, nil
+}
+
+// checkMode returns an error if the given mode is not a valid set of
+// permission bits.
+func checkMode(mode int) error {
+	if mode < 0 || mode > 0o7777 {
+		return fmt.Errorf("mode %o is not a valid file mode", mode)
+	}
+	return nil
+}
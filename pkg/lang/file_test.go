@@ -0,0 +1,145 @@
+package lang
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+const resources = "./testcases/resources"
+
+func TestFile_String(t *testing.T) {
+	values := map[starlark.Value]string{
+		File{Path: "/foo"}:                "<dep.File \"/foo\">",
+		Symlink{Src: "/foo", Dst: "/bar"}: "<dep.Symlink \"/bar\" -> \"/foo\">",
+		Directory{Path: "/foo"}:           "<dep.Directory \"/foo\">",
+		Absent{Path: "/foo"}:              "<dep.Absent \"/foo\">",
+	}
+
+	for value, want := range values {
+		if value.String() != want {
+			t.Errorf("wanted %s; got %s", want, value.String())
+		}
+
+		if _, err := value.Hash(); err == nil || !strings.HasPrefix(err.Error(), "unhashable type") {
+			t.Errorf("wanted %s to be unhashable", value.Type())
+		}
+	}
+}
+
+func TestFnFile(t *testing.T) {
+	args := starlark.Tuple{starlark.String("/tmp/foo")}
+	kwargs := []starlark.Tuple{
+		{starlark.String("content"), starlark.String("bar")},
+		{starlark.String("mode"), starlark.MakeInt(0600)},
+	}
+
+	value, err := FnFile(&starlark.Thread{}, starlark.NewBuiltin("file", FnFile), args, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnFile: %s", err)
+	}
+
+	f := value.(*File)
+	if f.Path != "/tmp/foo" {
+		t.Errorf("wanted path /tmp/foo; got %s", f.Path)
+	}
+
+	if f.Content == nil || *f.Content != "bar" {
+		t.Errorf("wanted content 'bar'; got %v", f.Content)
+	}
+
+	if f.Mode != 0600 {
+		t.Errorf("wanted mode 600; got %o", f.Mode)
+	}
+}
+
+func TestFnFile_invalid(t *testing.T) {
+	builtin := starlark.NewBuiltin("file", FnFile)
+
+	type testCase struct {
+		args   starlark.Tuple
+		kwargs []starlark.Tuple
+	}
+	testCases := []testCase{
+		{nil, nil},
+		{starlark.Tuple{starlark.String("")}, nil},
+		{starlark.Tuple{starlark.MakeInt(42)}, nil},
+		{starlark.Tuple{starlark.String("/foo")}, []starlark.Tuple{{starlark.String("content"), starlark.MakeInt(42)}}},
+		{starlark.Tuple{starlark.String("/foo")}, []starlark.Tuple{{starlark.String("mode"), starlark.MakeInt(010000)}}},
+		{starlark.Tuple{starlark.String("/foo")}, []starlark.Tuple{{starlark.String("owner"), starlark.String("root")}}},
+	}
+
+	for _, testCase := range testCases {
+		_, err := FnFile(&starlark.Thread{}, builtin, testCase.args, testCase.kwargs)
+		if err == nil {
+			t.Errorf("wanted error for args %+v, kwargs %+v", testCase.args, testCase.kwargs)
+		}
+	}
+}
+
+func TestFnSymlink_invalid(t *testing.T) {
+	builtin := starlark.NewBuiltin("symlink", FnSymlink)
+
+	args := starlark.Tuple{starlark.String("/foo")}
+	if _, err := FnSymlink(&starlark.Thread{}, builtin, args, nil); err == nil {
+		t.Error("wanted error for missing dst")
+	}
+}
+
+func TestParser_Resources(t *testing.T) {
+	parser := NewParser(resources)
+
+	err := parser.Run()
+	if err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	dep := pluckDep("all", parser.Deps())
+	if len(dep.MetCommands) != 5 {
+		t.Fatalf("wanted Dep 'all' to have 5 'met' commands; got %d", len(dep.MetCommands))
+	}
+
+	// relative paths are resolved relative to the module
+	dir, err := filepath.Abs(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := dep.MetCommands[0].(*Symlink)
+	if link.Src != filepath.Join(dir, "dotfiles/vimrc") || link.Dst != "~/.vimrc" {
+		t.Errorf("wanted link ~/.vimrc -> %s/dotfiles/vimrc; got %s", dir, link)
+	}
+
+	f := dep.MetCommands[1].(*File)
+	if f.Path != "/tmp/hushlogin" || f.Content == nil || *f.Content != "" || f.Mode != 0600 {
+		t.Errorf("wanted empty file /tmp/hushlogin with mode 600; got %+v", f)
+	}
+
+	d := dep.MetCommands[2].(*Directory)
+	if d.Path != "~/src" || d.Mode != 0755 {
+		t.Errorf("wanted directory ~/src with mode 755; got %+v", d)
+	}
+
+	a := dep.MetCommands[3].(*Absent)
+	if a.Path != filepath.Join(dir, "old") {
+		t.Errorf("wanted absent path %s/old; got %s", dir, a.Path)
+	}
+
+	// without a meet list, the resources in the met list are used
+	if len(dep.MeetCommands) != 4 {
+		t.Fatalf("wanted Dep 'all' to have 4 'meet' commands; got %d", len(dep.MeetCommands))
+	}
+	for i, cmd := range dep.MeetCommands {
+		if cmd != dep.MetCommands[i] {
+			t.Errorf("wanted 'meet' command #%d to be %s; got %s", i, dep.MetCommands[i], cmd)
+		}
+	}
+
+	// an explicit meet list is respected, even if empty
+	dep = pluckDep("explicit", parser.Deps())
+	if len(dep.MeetCommands) != 0 {
+		t.Errorf("wanted Dep 'explicit' to have no 'meet' commands; got %d", len(dep.MeetCommands))
+	}
+}
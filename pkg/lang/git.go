@@ -0,0 +1,79 @@
+package lang
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// Git represents the `git()` builtin function and models a clone of a git
+// repository, checked out at a given ref.
+//
+// The structure of `git` is as follows:
+//
+//   git(
+//     'https://github.com/nicktrav/matryoshka.git', // the URL of the repo
+//     '~/src/matryoshka',                           // the path of the clone
+//     ref='main',                                   // the branch, tag or
+//                                                   // commit to check out
+//                                                   // (defaults to the
+//                                                   // remote's default branch)
+//   )
+//
+// Relative destination paths are resolved relative to the directory of the
+// module in which the repository is declared. The URL is passed to git as is.
+type Git struct {
+
+	// URL is the URL of the repository.
+	URL string
+
+	// Dest is the path of the clone.
+	Dest string
+
+	// Ref is the branch, tag or commit to check out, or empty for the
+	// default branch of the repository.
+	Ref string
+}
+
+// String returns the string representation of the Git.
+func (g Git) String() string {
+	return fmt.Sprintf("<dep.Git %q -> %q>", g.URL, g.Dest)
+}
+
+// Type returns a short description about Git's type.
+func (g Git) Type() string { return "dep.Git" }
+
+// Freeze does nothing for a Git.
+func (g Git) Freeze() {}
+
+// Truth always returns true for a Git.
+func (g Git) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by Git.
+func (g Git) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", g.Type())
+}
+
+func (g Git) command()  {}
+func (g Git) resource() {}
+
+// FnGit implements the signature for a builtin function and implements the
+// functionality of the `git` function.
+func FnGit(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var url, dest, ref string
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "url", &url, "dest", &dest, "ref?", &ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("%s: url is empty", fn.Name())
+	}
+
+	resolved, err := resolvePath(t, dest)
+	if err != nil {
+		return nil, fmt.Errorf("%s: dest: %s", fn.Name(), err)
+	}
+
+	return &Git{URL: url, Dest: resolved, Ref: ref}, nil
+}
@@ -0,0 +1,40 @@
+package lang
+
+import (
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestFnGit(t *testing.T) {
+	builtin := starlark.NewBuiltin("git", FnGit)
+	args := starlark.Tuple{starlark.String("file:///tmp/repo.git"), starlark.String("/tmp/clone")}
+	kwargs := []starlark.Tuple{{starlark.String("ref"), starlark.String("v1")}}
+
+	value, err := FnGit(&starlark.Thread{}, builtin, args, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnGit: %s", err)
+	}
+
+	g := value.(*Git)
+	if g.URL != "file:///tmp/repo.git" || g.Dest != "/tmp/clone" || g.Ref != "v1" {
+		t.Errorf("wanted git file:///tmp/repo.git@v1 -> /tmp/clone; got %+v", g)
+	}
+}
+
+func TestFnGit_invalid(t *testing.T) {
+	builtin := starlark.NewBuiltin("git", FnGit)
+
+	testCases := []starlark.Tuple{
+		{starlark.String("file:///tmp/repo.git")},
+		{starlark.String(""), starlark.String("/tmp/clone")},
+		{starlark.String("file:///tmp/repo.git"), starlark.String("")},
+		{starlark.String("file:///tmp/repo.git"), starlark.String("/tmp/clone"), starlark.MakeInt(1)},
+	}
+
+	for _, args := range testCases {
+		if _, err := FnGit(&starlark.Thread{}, builtin, args, nil); err == nil {
+			t.Errorf("wanted error for args %+v", args)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package lang
+
+import (
+	"fmt"
+	"sort"
+
+	"go.starlark.net/syntax"
+)
+
+// Problem is a likely mistake found in a dep repository. Unlike an error,
+// a Problem does not prevent the deps from being applied.
+type Problem struct {
+
+	// Pos is the position at which the problem was found, if any.
+	Pos syntax.Position
+
+	// Message describes the problem.
+	Message string
+}
+
+// String returns the message of the Problem, prefixed with its position.
+func (p Problem) String() string {
+	if !p.Pos.IsValid() {
+		return p.Message
+	}
+	return fmt.Sprintf("%s: %s", p.Pos, p.Message)
+}
+
+// LintDeps returns the problems found in the given deps, along with any deps
+// they require, when applied from the enabled dep with the given root name.
+// The problems are sorted by position.
+//
+// The following are reported:
+//
+//   - enabled deps that are not reachable from the root
+//   - deps with meet commands, but no met commands, such that the meet
+//     commands are never run
+//   - enabled deps requiring disabled deps, which are ignored
+func LintDeps(deps []*Dep, root string) []Problem {
+	var all []*Dep
+	visited := make(map[*Dep]bool)
+
+	var visit func(dep *Dep)
+	visit = func(dep *Dep) {
+		if visited[dep] {
+			return
+		}
+		visited[dep] = true
+		all = append(all, dep)
+
+		for _, req := range dep.Requirements {
+			visit(req)
+		}
+	}
+	for _, dep := range deps {
+		visit(dep)
+	}
+
+	var problems []Problem
+	for _, dep := range all {
+		if !dep.Enable {
+			continue
+		}
+
+		if len(dep.MeetCommands) > 0 && len(dep.MetCommands) == 0 {
+			problems = append(problems, Problem{
+				Pos:     dep.Pos,
+				Message: fmt.Sprintf("dep %q has meet commands but no met commands; it is always satisfied, so its meet commands never run", dep.Name),
+			})
+		}
+
+		for _, req := range dep.Requirements {
+			if !req.Enable {
+				problems = append(problems, Problem{
+					Pos:     dep.Pos,
+					Message: fmt.Sprintf("dep %q requires dep %q, which is disabled and will be ignored", dep.Name, req.Name),
+				})
+			}
+		}
+	}
+
+	problems = append(problems, unreachable(all, root)...)
+
+	SortProblems(problems)
+	return problems
+}
+
+// SortProblems sorts the given problems by position. Problems without a
+// position sort first.
+func SortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		return positionLess(problems[i].Pos, problems[j].Pos)
+	})
+}
+
+// unreachable returns a problem for each of the given enabled deps that is
+// not reachable from the enabled dep with the given root name. Deps are
+// resolved by name, as in the dependency graph.
+func unreachable(deps []*Dep, root string) []Problem {
+	byName := make(map[string]*Dep)
+	for _, dep := range deps {
+		if dep.Enable {
+			if _, ok := byName[dep.Name]; !ok {
+				byName[dep.Name] = dep
+			}
+		}
+	}
+
+	if _, ok := byName[root]; !ok {
+		return []Problem{{Message: fmt.Sprintf("root dep %q is not defined", root)}}
+	}
+
+	reachable := make(map[string]bool)
+	var visit func(dep *Dep)
+	visit = func(dep *Dep) {
+		if dep == nil || reachable[dep.Name] {
+			return
+		}
+		reachable[dep.Name] = true
+
+		for _, req := range dep.Requirements {
+			visit(byName[req.Name])
+		}
+	}
+	visit(byName[root])
+
+	var problems []Problem
+	for _, dep := range deps {
+		if dep.Enable && !reachable[dep.Name] {
+			problems = append(problems, Problem{
+				Pos:     dep.Pos,
+				Message: fmt.Sprintf("dep %q is not reachable from root dep %q", dep.Name, root),
+			})
+		}
+	}
+	return problems
+}
+
+// LintModule returns the problems found in the source of the module with the
+// given filename, which are the symbols loaded via load() that are never
+// used. An error is returned if the source cannot be parsed.
+func LintModule(filename string, src interface{}) ([]Problem, error) {
+	f, err := syntax.Parse(filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// count the uses of each name, outside of load statements
+	uses := make(map[string]int)
+	var loads []*syntax.LoadStmt
+	syntax.Walk(f, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.LoadStmt:
+			loads = append(loads, n)
+			return false
+		case *syntax.Ident:
+			uses[n.Name]++
+		}
+		return true
+	})
+
+	var problems []Problem
+	for _, load := range loads {
+		for _, id := range load.To {
+			if uses[id.Name] == 0 {
+				problems = append(problems, Problem{
+					Pos:     id.NamePos,
+					Message: fmt.Sprintf("symbol %q loaded from %s is never used", id.Name, load.Module.Raw),
+				})
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,108 @@
+package lang
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const lint = "./testcases/lint"
+
+func TestParser_Modules(t *testing.T) {
+	parser := NewParser(lint)
+	if err := parser.Run(); err != nil {
+		t.Fatalf("parser.Run: %s", err)
+	}
+
+	var got []string
+	for _, module := range parser.Modules() {
+		got = append(got, filepath.Base(module))
+	}
+
+	want := []string{"foo.dep", "main.dep"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted modules %v; got %v", want, got)
+	}
+}
+
+func TestLintDeps(t *testing.T) {
+	parser := NewParser(lint)
+	if err := parser.Run(); err != nil {
+		t.Fatalf("parser.Run: %s", err)
+	}
+
+	want := []string{
+		`foo.dep:7:10: dep "foo" has meet commands but no met commands; it is always satisfied, so its meet commands never run`,
+		`foo.dep:7:10: dep "foo" requires dep "bar", which is disabled and will be ignored`,
+		`main.dep:9:13: dep "orphan" is not reachable from root dep "all"`,
+	}
+	assertProblems(t, LintDeps(parser.Deps(), "all"), want)
+}
+
+func TestLintDeps_MissingRoot(t *testing.T) {
+	parser := NewParser(lint)
+	if err := parser.Run(); err != nil {
+		t.Fatalf("parser.Run: %s", err)
+	}
+
+	problems := LintDeps(parser.Deps(), "missing")
+
+	want := `root dep "missing" is not defined`
+	if len(problems) == 0 || problems[0].String() != want {
+		t.Errorf("wanted first problem %q; got %v", want, problems)
+	}
+}
+
+func TestLintModule(t *testing.T) {
+	path := filepath.Join(lint, "main.dep")
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := LintModule(path, src)
+	if err != nil {
+		t.Fatalf("LintModule: %s", err)
+	}
+
+	want := []string{`main.dep:1:25: symbol "unused" loaded from "foo.dep" is never used`}
+	assertProblems(t, problems, want)
+}
+
+func TestLintModule_Aliased(t *testing.T) {
+	src := `
+load("foo.dep", bar = "foo", baz = "foo")
+
+all = dep(name = 'all', requires = [baz])
+`
+	problems, err := LintModule("main.dep", src)
+	if err != nil {
+		t.Fatalf("LintModule: %s", err)
+	}
+
+	want := []string{`main.dep:2:17: symbol "bar" loaded from "foo.dep" is never used`}
+	assertProblems(t, problems, want)
+}
+
+func TestLintModule_SyntaxError(t *testing.T) {
+	if _, err := LintModule("main.dep", "load("); err == nil {
+		t.Error("wanted error for invalid syntax")
+	}
+}
+
+// assertProblems checks that the given problems, with the path of each
+// position trimmed to the base name, are as wanted.
+func assertProblems(t *testing.T, problems []Problem, want []string) {
+	t.Helper()
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%s:%d:%d: %s", filepath.Base(p.Pos.Filename()), p.Pos.Line, p.Pos.Col, p.Message))
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted problems:\n%v\ngot:\n%v", want, got)
+	}
+}
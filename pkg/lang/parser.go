@@ -4,26 +4,57 @@ import (
 	"fmt"
 	oslib "os"
 	"path/filepath"
+	"sort"
 
 	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
 )
 
 const (
-	main  = "main.dep"
-	shell = "shell"
-	dep   = "dep"
-	os    = "os"
+	main      = "main.dep"
+	shell     = "shell"
+	dep       = "dep"
+	os        = "os"
+	retry     = "retry"
+	file      = "file"
+	symlink   = "symlink"
+	directory = "directory"
+	absent    = "absent"
+	template  = "template"
+	git       = "git"
+	pkg       = "pkg"
+	exec      = "exec"
 )
 
 var (
 	shellBuiltin = starlark.NewBuiltin(shell, FnShell)
 	depBuiltin   = starlark.NewBuiltin(dep, FnDep)
 	osBuiltin    = starlark.NewBuiltin(os, FnOs)
+	retryBuiltin = starlark.NewBuiltin(retry, FnRetry)
+
+	fileBuiltin      = starlark.NewBuiltin(file, FnFile)
+	symlinkBuiltin   = starlark.NewBuiltin(symlink, FnSymlink)
+	directoryBuiltin = starlark.NewBuiltin(directory, FnDirectory)
+	absentBuiltin    = starlark.NewBuiltin(absent, FnAbsent)
+	templateBuiltin  = starlark.NewBuiltin(template, FnTemplate)
+	gitBuiltin       = starlark.NewBuiltin(git, FnGit)
+	pkgBuiltin       = starlark.NewBuiltin(pkg, FnPkg)
+	execBuiltin      = starlark.NewBuiltin(exec, FnExec)
 
 	defaultModules = starlark.StringDict{
 		shell: shellBuiltin,
 		dep:   depBuiltin,
 		os:    osBuiltin,
+		retry: retryBuiltin,
+
+		file:      fileBuiltin,
+		symlink:   symlinkBuiltin,
+		directory: directoryBuiltin,
+		absent:    absentBuiltin,
+		template:  templateBuiltin,
+		git:       gitBuiltin,
+		pkg:       pkgBuiltin,
+		exec:      execBuiltin,
 	}
 )
 
@@ -33,13 +64,17 @@ type Parser interface {
 	// Run executes the parser.
 	Run() error
 
-	// Deps is a slice of pointers to parsed Deps.
+	// Deps is a slice of pointers to parsed Deps, in declaration order
+	// within each module, and in load order across modules.
 	Deps() []*Dep
+
+	// Modules is a sorted slice of the paths of the parsed modules.
+	Modules() []string
 }
 
 // NewParser returns a new Parser for the given root directory.
 func NewParser(root string) Parser {
-	return cachedParser{
+	return &cachedParser{
 		root:          root,
 		reader:        &localFileReader{root},
 		cache:         make(map[string]*cacheEntry),
@@ -67,6 +102,10 @@ type cachedParser struct {
 	// have already been parsed.
 	cache map[string]*cacheEntry
 
+	// order is the paths of the modules in the order in which they were
+	// first loaded, starting with the main module.
+	order []string
+
 	// root is the root directory with the entrypoint.
 	root string
 
@@ -78,7 +117,7 @@ type cachedParser struct {
 	customModules starlark.StringDict
 }
 
-func (s cachedParser) Run() error {
+func (s *cachedParser) Run() error {
 	// check for the main entrypoint
 	// TODO(nickt): remove the requirement to look for a main file
 	main := filepath.Join(s.root, main)
@@ -87,10 +126,21 @@ func (s cachedParser) Run() error {
 		return fmt.Errorf("main.dep not found")
 	}
 
+	// stack contains the edges of the load graph that lead to the module
+	// currently being loaded
+	var stack []Edge
+
+	// cycleErr records the first cycle found in the load graph. Errors
+	// returned from load are wrapped once for each module on the stack, so
+	// the cycle is returned directly from Run instead.
+	var cycleErr *CycleError
+
 	load := func(thread *starlark.Thread, moduleName string) (starlark.StringDict, error) {
 		var fromPath string
+		var loadPos syntax.Position
 		if thread.CallStackDepth() > 0 {
-			fromPath = thread.CallFrame(0).Pos.Filename()
+			loadPos = thread.CallFrame(0).Pos
+			fromPath = loadPos.Filename()
 		}
 		modulePath, err := s.reader.Resolve(moduleName, fromPath)
 		if err != nil {
@@ -102,7 +152,11 @@ func (s cachedParser) Run() error {
 			return e.globals, e.err
 		}
 		if ok {
-			return nil, fmt.Errorf("cycle in load graph")
+			cycle := loadCycle(stack, Edge{From: fromPath, To: modulePath, Pos: loadPos})
+			if cycleErr == nil {
+				cycleErr = cycle
+			}
+			return nil, cycle
 		}
 
 		moduleSource, err := s.reader.ReadFile(modulePath)
@@ -111,9 +165,12 @@ func (s cachedParser) Run() error {
 			return nil, err
 		}
 
+		stack = append(stack, Edge{From: fromPath, To: modulePath, Pos: loadPos})
 		s.cache[modulePath] = nil
+		s.order = append(s.order, modulePath)
 		globals, err := starlark.ExecFile(thread, modulePath, moduleSource, s.customModules)
 		s.cache[modulePath] = &cacheEntry{globals, err}
+		stack = stack[:len(stack)-1]
 
 		return globals, err
 	}
@@ -122,23 +179,74 @@ func (s cachedParser) Run() error {
 	thread := &starlark.Thread{Load: load}
 	_, err = load(thread, main)
 
+	if cycleErr != nil {
+		return cycleErr
+	}
+
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return ValidateDeps(s.Deps())
+}
+
+// loadCycle returns a CycleError for the cycle closed by the given edge. The
+// stack contains the edges leading to the module currently being loaded, one
+// of which must start at the module the closing edge ends at.
+func loadCycle(stack []Edge, closing Edge) *CycleError {
+	start := len(stack)
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].From == closing.To {
+			start = i
+			break
+		}
+	}
+
+	edges := append([]Edge{}, stack[start:]...)
+	edges = append(edges, closing)
+	return &CycleError{Graph: "load", Edges: edges}
 }
 
 // Deps flattens the deps parsed across all modules and returns a slice of
-// pointers to them.
-func (s cachedParser) Deps() []*Dep {
+// pointers to them. Modules are visited in the order in which they were first
+// loaded, and the deps bound to the global variables of each module are
+// ordered by the position of their declaration. A dep bound to more than one
+// variable is only returned once.
+func (s *cachedParser) Deps() []*Dep {
 	var deps []*Dep
-	for _, entry := range s.cache {
-		for _, global := range entry.globals {
-			if dep, ok := global.(*Dep); ok {
-				deps = append(deps, dep)
+	seen := make(map[*Dep]bool)
+	for _, path := range s.order {
+		entry := s.cache[path]
+		if entry == nil {
+			continue
+		}
+
+		// globals are visited by name, such that deps declared at the same
+		// position, e.g. by a function called more than once, are ordered
+		var moduleDeps []*Dep
+		for _, name := range entry.globals.Keys() {
+			if dep, ok := entry.globals[name].(*Dep); ok && !seen[dep] {
+				seen[dep] = true
+				moduleDeps = append(moduleDeps, dep)
 			}
 		}
+		sort.SliceStable(moduleDeps, func(i, j int) bool {
+			return positionLess(moduleDeps[i].Pos, moduleDeps[j].Pos)
+		})
+
+		deps = append(deps, moduleDeps...)
 	}
 	return deps
 }
+
+// Modules returns the paths of the modules that were parsed, sorted.
+func (s *cachedParser) Modules() []string {
+	var modules []string
+	for path, entry := range s.cache {
+		if entry != nil && entry.err == nil {
+			modules = append(modules, path)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
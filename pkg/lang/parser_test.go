@@ -2,7 +2,10 @@ package lang
 
 import (
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -10,6 +13,7 @@ const (
 	noMain     = "./testcases/nomain"
 	simpleMain = "./testcases/simple_main"
 	multiFile  = "./testcases/multi_file"
+	cycleLoad  = "./testcases/load_cycle"
 )
 
 func TestParser_NoMain(t *testing.T) {
@@ -56,8 +60,8 @@ func TestParser_SimpleMain(t *testing.T) {
 	}
 
 	want := fmt.Sprintf("echo 'Hello, %s!'", runtime.GOOS)
-	got := dep.MetCommands[0].Command
-	if dep.MetCommands[0].Command != want {
+	got := dep.MetCommands[0].(*ShellCmd).Command
+	if dep.MetCommands[0].(*ShellCmd).Command != want {
 		t.Errorf("wanted Dep 'all' 'met' command to be '%s'; got '%s'", want, got)
 	}
 
@@ -66,13 +70,13 @@ func TestParser_SimpleMain(t *testing.T) {
 	}
 
 	want = "echo 'Hello, indeed!'"
-	got = dep.MeetCommands[0].Command
+	got = dep.MeetCommands[0].(*ShellCmd).Command
 	if want != got {
 		t.Errorf("wanted Dep 'all' 'meet' command #1 to be '%s'; got '%s'", want, got)
 	}
 
 	want = "echo 'Hello, again!'"
-	got = dep.MeetCommands[1].Command
+	got = dep.MeetCommands[1].(*ShellCmd).Command
 	if want != got {
 		t.Errorf("wanted Dep 'all' 'meet' command #2 to be '%s'; got '%s'", want, got)
 	}
@@ -166,6 +170,83 @@ func TestParser_MultiFile(t *testing.T) {
 	}
 }
 
+func TestParser_Deps_Order(t *testing.T) {
+	// modules are visited in the order in which they are first loaded, i.e.
+	// main.dep, foo.dep, baz.dep (loaded by foo.dep), and bar.dep, and deps
+	// in the order in which they are declared within each module
+	want := []string{"all", "bam", "foo", "baz", "bar"}
+
+	for i := 0; i < 10; i++ {
+		parser := NewParser(multiFile)
+		if err := parser.Run(); err != nil {
+			t.Fatalf("parser.Run: %s", err)
+		}
+
+		var got []string
+		for _, dep := range parser.Deps() {
+			got = append(got, dep.Name)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("wanted deps in order %v; got %v", want, got)
+		}
+	}
+}
+
+func TestParser_LoadCycle(t *testing.T) {
+	parser := NewParser(cycleLoad)
+
+	err := parser.Run()
+	if err == nil {
+		t.Fatal("wanted an error")
+	}
+
+	cycleErr, ok := err.(*CycleError)
+	if !ok {
+		t.Fatalf("wanted a CycleError; got %+v", err)
+	}
+
+	foo := filepath.Join(cycleLoad, "foo.dep")
+	bar := filepath.Join(cycleLoad, "bar.dep")
+	want := []Edge{
+		{From: foo, To: bar},
+		{From: bar, To: foo},
+	}
+	if len(cycleErr.Edges) != len(want) {
+		t.Fatalf("wanted %d edges; got %+v", len(want), cycleErr.Edges)
+	}
+
+	for i, edge := range cycleErr.Edges {
+		if edge.From != want[i].From || edge.To != want[i].To {
+			t.Errorf("wanted edge %s -> %s; got %s -> %s", want[i].From, want[i].To, edge.From, edge.To)
+		}
+
+		// each load statement is on the third line of the module
+		if edge.Pos.Filename() != edge.From || edge.Pos.Line != 3 {
+			t.Errorf("wanted edge declared at %s:3; got %s", edge.From, edge.Pos)
+		}
+	}
+
+	if !strings.HasPrefix(err.Error(), "cycle in load graph: "+foo+" -> "+bar+" -> "+foo) {
+		t.Errorf("wanted error to contain the full cycle; got %s", err)
+	}
+}
+
+func TestParser_DepPosition(t *testing.T) {
+	parser := NewParser(simpleMain)
+
+	err := parser.Run()
+	if err != nil {
+		t.Fatalf("did not expect error %s", err)
+	}
+
+	dep := pluckDep("all", parser.Deps())
+	pos := dep.Pos
+	if pos.Filename() != filepath.Join(simpleMain, "main.dep") || pos.Line != 13 {
+		t.Errorf("wanted dep 'all' declared at line 13 of main.dep; got %s", pos)
+	}
+}
+
 func toMap(deps []*Dep) map[string]*Dep {
 	depMap := make(map[string]*Dep)
 
@@ -0,0 +1,102 @@
+package lang
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// PackageManagers are the names of the package managers that may be given a
+// package name of their own in the `pkg()` builtin function.
+var PackageManagers = []string{"apt", "dnf", "pacman", "brew"}
+
+// Package is a Resource modelling a package installed by the package manager
+// of the local system.
+type Package struct {
+
+	// Name is the name of the package.
+	Name string
+
+	// Names maps the name of a package manager to the name of the package
+	// for that package manager, where it differs from Name.
+	Names map[string]string
+}
+
+// NameFor returns the name of the package for the given package manager.
+func (p Package) NameFor(manager string) string {
+	if name, ok := p.Names[manager]; ok {
+		return name
+	}
+	return p.Name
+}
+
+// String returns the string representation of the Package.
+func (p Package) String() string {
+	return fmt.Sprintf("<dep.Package %q>", p.Name)
+}
+
+// Type returns a short description about Package's type.
+func (p Package) Type() string { return "dep.Package" }
+
+// Freeze does nothing for a Package.
+func (p Package) Freeze() {}
+
+// Truth always returns true for a Package.
+func (p Package) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by Package.
+func (p Package) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", p.Type())
+}
+
+func (p Package) command()  {}
+func (p Package) resource() {}
+
+// FnPkg implements the signature for a builtin function and implements the
+// functionality of the `pkg` function, which returns a Dep that installs a
+// package using whichever package manager is available on the local system.
+//
+// The structure of `pkg` is as follows:
+//
+//   pkg(
+//     'ripgrep',      // the name of the package, and of the dep
+//     apt='ripgrep',  // the name of the package for each package manager,
+//     dnf='ripgrep',  // where it differs from the name of the package
+//     pacman='ripgrep',
+//     brew='ripgrep',
+//   )
+//
+// The dep returned can be used like any other, e.g. as a requirement of
+// another dep.
+func FnPkg(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	names := make([]string, len(PackageManagers))
+
+	pairs := []interface{}{"name", &name}
+	for i, manager := range PackageManagers {
+		pairs = append(pairs, manager+"?", &names[i])
+	}
+
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, pairs...); err != nil {
+		return nil, err
+	}
+
+	if err := checkName(name); err != nil {
+		return nil, positionError(callerPos(t), fmt.Errorf("%s: %w", fn.Name(), err))
+	}
+
+	p := &Package{Name: name, Names: make(map[string]string)}
+	for i, manager := range PackageManagers {
+		if names[i] != "" {
+			p.Names[manager] = names[i]
+		}
+	}
+
+	return &Dep{
+		Name:         name,
+		MetCommands:  []Command{p},
+		MeetCommands: []Command{p},
+		Enable:       true,
+		Pos:          callerPos(t),
+	}, nil
+}
@@ -0,0 +1,63 @@
+package lang
+
+import (
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestFnPkg(t *testing.T) {
+	builtin := starlark.NewBuiltin("pkg", FnPkg)
+	args := starlark.Tuple{starlark.String("fd")}
+	kwargs := []starlark.Tuple{
+		{starlark.String("apt"), starlark.String("fd-find")},
+		{starlark.String("brew"), starlark.String("fd")},
+	}
+
+	value, err := FnPkg(&starlark.Thread{}, builtin, args, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnPkg: %s", err)
+	}
+
+	dep := value.(*Dep)
+	if dep.Name != "fd" || !dep.Enable {
+		t.Errorf("wanted enabled dep 'fd'; got %+v", dep)
+	}
+
+	if len(dep.MetCommands) != 1 || len(dep.MeetCommands) != 1 {
+		t.Fatalf("wanted a single met and meet command; got %+v and %+v", dep.MetCommands, dep.MeetCommands)
+	}
+
+	p := dep.MetCommands[0].(*Package)
+	if dep.MeetCommands[0] != Command(p) {
+		t.Errorf("wanted the same package to be met and meet; got %s", dep.MeetCommands[0])
+	}
+
+	wanted := map[string]string{"apt": "fd-find", "dnf": "fd", "pacman": "fd", "brew": "fd"}
+	for manager, name := range wanted {
+		if p.NameFor(manager) != name {
+			t.Errorf("wanted name %s for %s; got %s", name, manager, p.NameFor(manager))
+		}
+	}
+}
+
+func TestFnPkg_invalid(t *testing.T) {
+	builtin := starlark.NewBuiltin("pkg", FnPkg)
+
+	type testCase struct {
+		args   starlark.Tuple
+		kwargs []starlark.Tuple
+	}
+	testCases := []testCase{
+		{nil, nil},
+		{starlark.Tuple{starlark.String("")}, nil},
+		{starlark.Tuple{starlark.String("fd")}, []starlark.Tuple{{starlark.String("apk"), starlark.String("fd")}}},
+		{starlark.Tuple{starlark.String("fd")}, []starlark.Tuple{{starlark.String("apt"), starlark.MakeInt(1)}}},
+	}
+
+	for _, testCase := range testCases {
+		if _, err := FnPkg(&starlark.Thread{}, builtin, testCase.args, testCase.kwargs); err == nil {
+			t.Errorf("wanted error for args %+v, kwargs %+v", testCase.args, testCase.kwargs)
+		}
+	}
+}
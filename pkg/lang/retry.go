@@ -0,0 +1,133 @@
+package lang
+
+import (
+	"fmt"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+const (
+	attemptsArg  = starlark.String("attempts")
+	backoffArg   = starlark.String("backoff")
+	exitCodesArg = starlark.String("exit_codes")
+
+	defaultAttempts = 3
+)
+
+// Retry represents the `retry()` builtin function and models the policy for
+// retrying the meet commands of a dependency when they fail.
+//
+// The structure of `retry` is as follows:
+//
+//   retry(
+//     attempts=3,      // the total number of attempts (defaults to 3)
+//     backoff='5s',    // the delay before the second attempt, doubled for
+//                      // each subsequent attempt, either as a number of
+//                      // seconds or a duration string (defaults to none)
+//     exit_codes=[1],  // the exit codes for which a failed command is
+//                      // retried (defaults to any exit code)
+//   )
+//
+type Retry struct {
+
+	// Attempts is the total number of times the meet commands are attempted.
+	Attempts int
+
+	// Backoff is the delay before the second attempt. The delay is doubled
+	// for each subsequent attempt.
+	Backoff time.Duration
+
+	// ExitCodes are the exit codes for which a failed command will be
+	// retried. If empty, a command failing with any exit code is retried.
+	ExitCodes []int
+}
+
+// String returns the string representation of the Retry.
+func (r Retry) String() string {
+	return fmt.Sprintf("<dep.Retry attempts=%d backoff=%s>", r.Attempts, r.Backoff)
+}
+
+// Type returns a short description about Retry's type.
+func (r Retry) Type() string { return "dep.Retry" }
+
+// Freeze does nothing for a Retry.
+func (r Retry) Freeze() {}
+
+// Truth always returns true for a Retry.
+func (r Retry) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by Retry.
+func (r Retry) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", r.Type())
+}
+
+// FnRetry implements the signature for a builtin function and implements
+// the functionality of the `retry` function.
+//
+// FnRetry transforms the keyword arguments into a Retry object after
+// performing validation on the arguments.
+func FnRetry(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("retry: unexpected positional arguments")
+	}
+
+	retry := Retry{Attempts: defaultAttempts}
+	for _, kwarg := range kwargs {
+		key := kwarg.Index(0)
+		value := kwarg.Index(1)
+		switch key {
+
+		case attemptsArg:
+			attempts, err := starlark.AsInt32(value)
+			if err != nil || attempts < 1 {
+				return nil, fmt.Errorf("retry: argument to attempts is not a positive integer")
+			}
+			retry.Attempts = attempts
+
+		case backoffArg:
+			backoff, err := asDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("retry: argument to backoff: %s", err)
+			}
+			retry.Backoff = backoff
+
+		case exitCodesArg:
+			codes, err := asIntList(value)
+			if err != nil {
+				return nil, fmt.Errorf("retry: argument to exit_codes: %s", err)
+			}
+			retry.ExitCodes = codes
+
+		default:
+			return nil, fmt.Errorf("retry: unexpected keyword argument %s", key)
+		}
+	}
+
+	return retry, nil
+}
+
+// asIntList returns the given list value as a slice of ints. An error is
+// returned if the value is not a list of integers.
+func asIntList(value starlark.Value) ([]int, error) {
+	list, ok := value.(*starlark.List)
+	if !ok {
+		return nil, fmt.Errorf("value %v is not a list", value)
+	}
+
+	var ints []int
+
+	iter := list.Iterate()
+	defer iter.Done()
+
+	var v starlark.Value
+	for iter.Next(&v) {
+		i, err := starlark.AsInt32(v)
+		if err != nil {
+			return nil, fmt.Errorf("list item %v is not an integer", v)
+		}
+		ints = append(ints, i)
+	}
+
+	return ints, nil
+}
@@ -0,0 +1,120 @@
+package lang
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+func TestRetry_String(t *testing.T) {
+	retry := Retry{Attempts: 3, Backoff: time.Second}
+
+	want := "<dep.Retry attempts=3 backoff=1s>"
+	if retry.String() != want {
+		t.Errorf("wanted %s, got %s", want, retry.String())
+	}
+}
+
+func TestRetry_Hash(t *testing.T) {
+	_, err := Retry{}.Hash()
+	if err == nil {
+		t.Fatal("wanted Hash to return an error")
+	}
+
+	if !strings.HasPrefix(err.Error(), "unhashable type") {
+		t.Errorf("wanted error to contain 'unhashable type")
+	}
+}
+
+func TestFnRetry_defaults(t *testing.T) {
+	value, err := FnRetry(&starlark.Thread{}, &starlark.Builtin{}, nil, nil)
+	if err != nil {
+		t.Fatalf("error running FnRetry: %s", err)
+	}
+
+	retry, ok := value.(Retry)
+	if !ok {
+		t.Fatalf("returned value %s was not a Retry", value)
+	}
+
+	if retry.Attempts != defaultAttempts {
+		t.Errorf("wanted %d attempts; got %d", defaultAttempts, retry.Attempts)
+	}
+
+	if retry.Backoff != 0 || len(retry.ExitCodes) != 0 {
+		t.Errorf("wanted no backoff or exit codes; got %+v", retry)
+	}
+}
+
+func TestFnRetry(t *testing.T) {
+	kwargs := []starlark.Tuple{
+		{attemptsArg, starlark.MakeInt(5)},
+		{backoffArg, starlark.String("2s")},
+		{exitCodesArg, starlark.NewList([]starlark.Value{starlark.MakeInt(1), starlark.MakeInt(75)})},
+	}
+
+	value, err := FnRetry(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnRetry: %s", err)
+	}
+
+	retry := value.(Retry)
+	if retry.Attempts != 5 {
+		t.Errorf("wanted 5 attempts; got %d", retry.Attempts)
+	}
+
+	if retry.Backoff != 2*time.Second {
+		t.Errorf("wanted backoff 2s; got %s", retry.Backoff)
+	}
+
+	if len(retry.ExitCodes) != 2 || retry.ExitCodes[0] != 1 || retry.ExitCodes[1] != 75 {
+		t.Errorf("wanted exit codes [1 75]; got %+v", retry.ExitCodes)
+	}
+}
+
+func TestFnRetry_invalid(t *testing.T) {
+	kwargsList := [][]starlark.Tuple{
+		{{attemptsArg, starlark.MakeInt(0)}},
+		{{attemptsArg, starlark.String("3")}},
+		{{backoffArg, starlark.String("soon")}},
+		{{exitCodesArg, starlark.MakeInt(1)}},
+		{{exitCodesArg, starlark.NewList([]starlark.Value{starlark.String("1")})}},
+		{{starlark.String("foo"), starlark.MakeInt(1)}},
+	}
+
+	for _, kwargs := range kwargsList {
+		_, err := FnRetry(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+		if err == nil {
+			t.Errorf("wanted error for kwargs %+v", kwargs)
+		}
+	}
+
+	args := starlark.Tuple{starlark.MakeInt(3)}
+	if _, err := FnRetry(&starlark.Thread{}, &starlark.Builtin{}, args, nil); err == nil {
+		t.Error("wanted error for positional arguments")
+	}
+}
+
+func TestFnDep_retry(t *testing.T) {
+	kwargs := []starlark.Tuple{
+		{argName, starlark.String("foo")},
+		{argRetry, Retry{Attempts: 2}},
+	}
+
+	value, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnDep: %s", err)
+	}
+
+	dep := value.(*Dep)
+	if dep.Retry == nil || dep.Retry.Attempts != 2 {
+		t.Errorf("wanted retry policy with 2 attempts; got %+v", dep.Retry)
+	}
+
+	kwargs = []starlark.Tuple{{argRetry, starlark.MakeInt(2)}}
+	if _, err := FnDep(&starlark.Thread{}, &starlark.Builtin{}, nil, kwargs); err == nil {
+		t.Error("wanted error for retry that is not a retry policy")
+	}
+}
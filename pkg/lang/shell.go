@@ -2,6 +2,8 @@ package lang
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"go.starlark.net/starlark"
 )
@@ -11,6 +13,14 @@ const (
 	defaultShell = "bash"
 
 	loginArg = starlark.String("login")
+
+	timeoutArg = starlark.String("timeout")
+
+	envArg      = starlark.String("env")
+	cwdArg      = starlark.String("cwd")
+	clearEnvArg = starlark.String("clear_env")
+
+	sudoArg = starlark.String("sudo")
 )
 
 // ShellCmd represents the `shell()` builtin function and represents a command
@@ -22,18 +32,58 @@ const (
 //     'echo 42',   // the shell command to run
 //     shell='bash' // the shell to run the command in (defaults to 'bash')
 //     login=False  // the shell is a login shell (defaults to 'False')
+//     timeout='5m' // the maximum time the command may run for, either as
+//                  // a number of seconds or a duration string (defaults
+//                  // to no limit)
+//     env={}       // variables added to the environment of the command,
+//                  // e.g. {'GOPATH': '~/go'} (defaults to no variables)
+//     cwd='src'    // the working directory of the command, relative to
+//                  // the module (defaults to the working directory of
+//                  // matryoshka)
+//     clear_env=False // the command starts with an empty environment,
+//                     // rather than inheriting that of matryoshka
+//                     // (defaults to 'False')
+//     sudo=False   // the command runs as root via sudo (defaults to
+//                  // 'False')
 //   )
 //
-// TODO(nickt): Support varargs
+// Given more than one positional argument, e.g. shell('git', 'clone', url),
+// the arguments are an argument vector that is run directly, without a
+// shell, such that no quoting is required. See the `exec` function.
 type ShellCmd struct {
-	// Command is the shell command to execute.
+	// Command is the shell command to execute. For an argument vector,
+	// Command is the arguments quoted for display.
 	Command string
 
+	// Argv is the argument vector to run directly, without a shell, or empty
+	// to run Command in a shell.
+	Argv []string
+
 	// Shell is the shell to run the command in.
 	Shell string
 
 	// Login indicates whether this command should run in a login shell or not.
 	Login bool
+
+	// Timeout is the maximum length of time the command may run for. A zero
+	// Timeout means there is no limit.
+	Timeout time.Duration
+
+	// Env contains variables added to the environment of the command,
+	// overriding any inherited variables of the same name.
+	Env map[string]string
+
+	// Cwd is the working directory of the command, or empty to use the
+	// working directory of the current process.
+	Cwd string
+
+	// ClearEnv indicates whether the command starts with an empty
+	// environment, rather than inheriting the environment of the current
+	// process.
+	ClearEnv bool
+
+	// Sudo indicates whether the command runs as root via sudo.
+	Sudo bool
 }
 
 // String returns the string representation of the ShellCmd.
@@ -55,47 +105,250 @@ func (s ShellCmd) Hash() (uint32, error) {
 	return 0, fmt.Errorf("unhashable type: %s", s.Type())
 }
 
+func (s ShellCmd) command() {}
+
+// WithSudo returns a copy of the ShellCmd that runs as root via sudo.
+func (s ShellCmd) WithSudo() *ShellCmd {
+	s.Sudo = true
+	return &s
+}
+
+// WithEnv returns a copy of the ShellCmd with the given variables added to its
+// environment. Variables already in the environment of the ShellCmd take
+// precedence over the given variables.
+func (s ShellCmd) WithEnv(env map[string]string) *ShellCmd {
+	merged := make(map[string]string, len(env)+len(s.Env))
+	for k, v := range env {
+		merged[k] = v
+	}
+	for k, v := range s.Env {
+		merged[k] = v
+	}
+	s.Env = merged
+	return &s
+}
+
 // FnShell implements the signature for a builtin function and implements
 // the functionality of the `shell` function.
 //
 // FnShell transforms the arguments into a ShellCmd object after performing
-// validation on the arguments.
-// TODO(nickt): Validate arguments
+// validation on the arguments. Given a single positional argument, the
+// argument is run as a shell command. Given more than one, the arguments are
+// an argument vector run directly, as with the `exec` function.
 func FnShell(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-	value := args.Index(0)
-	strValue, ok := value.(starlark.String)
-	if !ok {
-		return nil, fmt.Errorf("expected %v to be ok type String", value)
+	cmd, err := newShellCmd(t, shell, args, kwargs, len(args) > 1)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// FnExec implements the signature for a builtin function and implements the
+// functionality of the `exec` function, which runs a program directly with the
+// given argument vector, without a shell. The first argument is the program,
+// which is looked up on the PATH if it does not contain a path separator.
+//
+// The structure of `exec` is as follows:
+//
+//   exec(
+//     'git', 'clone', url, dest, // the program and its arguments
+//     timeout='5m',              // as for `shell`, as are env, cwd,
+//                                // clear_env and sudo
+//   )
+func FnExec(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	cmd, err := newShellCmd(t, exec, args, kwargs, true)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// newShellCmd returns a ShellCmd from the arguments to the builtin with the
+// given name. If argv is true, the positional arguments are an argument vector
+// to run directly. Otherwise there must be a single positional argument, which
+// is run as a shell command.
+func newShellCmd(t *starlark.Thread, name string, args starlark.Tuple, kwargs []starlark.Tuple, argv bool) (ShellCmd, error) {
+	cmd := ShellCmd{Shell: defaultShell}
+
+	if len(args) == 0 {
+		return cmd, fmt.Errorf("%s: missing command", name)
+	}
+
+	var strArgs []string
+	for i, arg := range args {
+		s, ok := arg.(starlark.String)
+		if !ok {
+			return cmd, fmt.Errorf("%s: argument %d is not a string (got %s)", name, i+1, arg.Type())
+		}
+		strArgs = append(strArgs, string(s))
+	}
+
+	if strArgs[0] == "" {
+		return cmd, fmt.Errorf("%s: command is empty", name)
+	}
+
+	if argv {
+		cmd.Argv = strArgs
+		cmd.Command = quoteArgs(strArgs)
+	} else {
+		cmd.Command = strArgs[0]
 	}
 
-	shell := defaultShell
-	login := false
 	for _, kwarg := range kwargs {
 		key := kwarg.Index(0)
 		value := kwarg.Index(1)
 		switch key {
 
 		case shellArg:
+			if argv {
+				return cmd, fmt.Errorf("%s: shell cannot be used with an argument vector", name)
+			}
 			s, ok := starlark.AsString(value)
 			if !ok {
-				return nil, fmt.Errorf("shell: argument to shell is not a string")
+				return cmd, fmt.Errorf("%s: argument to shell is not a string", name)
 			}
-			shell = s
+			cmd.Shell = s
 
 		case loginArg:
+			if argv {
+				return cmd, fmt.Errorf("%s: login cannot be used with an argument vector", name)
+			}
 			s, ok := value.(starlark.Bool)
 			if !ok {
-				return nil, fmt.Errorf("shell: argument to login is not a boolean")
+				return cmd, fmt.Errorf("%s: argument to login is not a boolean", name)
 			}
-			login = s == starlark.True
+			cmd.Login = s == starlark.True
+
+		case timeoutArg:
+			d, err := asDuration(value)
+			if err != nil {
+				return cmd, fmt.Errorf("%s: argument to timeout: %s", name, err)
+			}
+			cmd.Timeout = d
+
+		case envArg:
+			e, err := asEnv(value)
+			if err != nil {
+				return cmd, fmt.Errorf("%s: argument to env: %s", name, err)
+			}
+			cmd.Env = e
+
+		case cwdArg:
+			c, ok := starlark.AsString(value)
+			if !ok {
+				return cmd, fmt.Errorf("%s: argument to cwd is not a string", name)
+			}
+			resolved, err := resolvePath(t, c)
+			if err != nil {
+				return cmd, fmt.Errorf("%s: argument to cwd: %s", name, err)
+			}
+			cmd.Cwd = resolved
+
+		case clearEnvArg:
+			c, ok := value.(starlark.Bool)
+			if !ok {
+				return cmd, fmt.Errorf("%s: argument to clear_env is not a boolean", name)
+			}
+			cmd.ClearEnv = c == starlark.True
+
+		case sudoArg:
+			s, ok := value.(starlark.Bool)
+			if !ok {
+				return cmd, fmt.Errorf("%s: argument to sudo is not a boolean", name)
+			}
+			cmd.Sudo = s == starlark.True
+
+		default:
+			return cmd, fmt.Errorf("%s: unexpected keyword argument %s", name, key)
 		}
 	}
 
-	cmd := ShellCmd{
-		Command: string(strValue),
-		Shell:   shell,
-		Login:   login,
+	return cmd, nil
+}
+
+// quoteArgs returns the given argument vector as a single string, quoting
+// each argument as necessary for it to be read back by a Bourne shell.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg returns the given argument, single-quoted if it contains anything
+// other than characters that are safe to use unquoted in a Bourne shell.
+func quoteArg(arg string) string {
+	if arg == "" {
+		return "''"
 	}
 
-	return cmd, nil
+	safe := true
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:@%+,", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// asDuration returns the given value as a time.Duration. The value may be
+// either a number of seconds, or a string in the format accepted by
+// time.ParseDuration. An error is returned if the value is neither, or is
+// negative.
+func asDuration(value starlark.Value) (time.Duration, error) {
+	var d time.Duration
+	switch v := value.(type) {
+	case starlark.Int:
+		seconds, ok := v.Int64()
+		if !ok {
+			return 0, fmt.Errorf("value %v is out of range", value)
+		}
+		d = time.Duration(seconds) * time.Second
+	case starlark.Float:
+		d = time.Duration(float64(v) * float64(time.Second))
+	case starlark.String:
+		parsed, err := time.ParseDuration(string(v))
+		if err != nil {
+			return 0, fmt.Errorf("value %v is not a duration", value)
+		}
+		d = parsed
+	default:
+		return 0, fmt.Errorf("value %v is not a duration", value)
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("value %v is negative", value)
+	}
+	return d, nil
+}
+
+// asEnv returns the given value as a map of environment variables. The value
+// must be a dict of strings to strings, and the names of the variables must
+// be non-empty and must not contain '='.
+func asEnv(value starlark.Value) (map[string]string, error) {
+	dict, ok := value.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("value %v is not a dict", value)
+	}
+
+	env := make(map[string]string, dict.Len())
+	for _, item := range dict.Items() {
+		k, ok := starlark.AsString(item[0])
+		if !ok || k == "" || strings.Contains(k, "=") {
+			return nil, fmt.Errorf("%v is not a valid variable name", item[0])
+		}
+
+		v, ok := starlark.AsString(item[1])
+		if !ok {
+			return nil, fmt.Errorf("value of %s is not a string", k)
+		}
+		env[k] = v
+	}
+	return env, nil
 }
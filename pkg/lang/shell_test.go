@@ -1,8 +1,10 @@
 package lang
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"go.starlark.net/starlark"
 )
@@ -128,3 +130,234 @@ func TestFnShell_nonDefaultLogin(t *testing.T) {
 		t.Error("wanted login to be true; got false")
 	}
 }
+
+func TestFnShell_timeout(t *testing.T) {
+	thread := &starlark.Thread{}
+	builtin := &starlark.Builtin{}
+
+	type testCase struct {
+		value starlark.Value
+		want  time.Duration
+	}
+	testCases := []testCase{
+		{starlark.MakeInt(5), 5 * time.Second},
+		{starlark.Float(0.5), 500 * time.Millisecond},
+		{starlark.String("2m"), 2 * time.Minute},
+	}
+
+	for _, testCase := range testCases {
+		args := []starlark.Value{starlark.String("foo")}
+		kwargs := []starlark.Tuple{{starlark.String("timeout"), testCase.value}}
+
+		value, err := FnShell(thread, builtin, args, kwargs)
+		if err != nil {
+			t.Fatalf("error running FnShell with kwargs %+v: %s", kwargs, err)
+		}
+
+		cmd := value.(ShellCmd)
+		if cmd.Timeout != testCase.want {
+			t.Errorf("wanted timeout %s; got %s", testCase.want, cmd.Timeout)
+		}
+	}
+}
+
+func TestFnShell_invalidTimeout(t *testing.T) {
+	thread := &starlark.Thread{}
+	builtin := &starlark.Builtin{}
+
+	values := []starlark.Value{
+		starlark.String("soon"),
+		starlark.MakeInt(-1),
+		starlark.Bool(true),
+	}
+
+	for _, value := range values {
+		args := []starlark.Value{starlark.String("foo")}
+		kwargs := []starlark.Tuple{{starlark.String("timeout"), value}}
+
+		_, err := FnShell(thread, builtin, args, kwargs)
+		if err == nil {
+			t.Errorf("wanted error for timeout %s", value)
+		}
+	}
+}
+
+func TestFnShell_env(t *testing.T) {
+	thread := &starlark.Thread{}
+	builtin := &starlark.Builtin{}
+
+	env := starlark.NewDict(1)
+	_ = env.SetKey(starlark.String("FOO"), starlark.String("bar"))
+
+	args := []starlark.Value{starlark.String("foo")}
+	kwargs := []starlark.Tuple{
+		{starlark.String("env"), env},
+		{starlark.String("cwd"), starlark.String("/tmp")},
+		{starlark.String("clear_env"), starlark.True},
+	}
+
+	value, err := FnShell(thread, builtin, args, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnShell: %s", err)
+	}
+
+	cmd := value.(ShellCmd)
+	if len(cmd.Env) != 1 || cmd.Env["FOO"] != "bar" {
+		t.Errorf("wanted env FOO=bar; got %+v", cmd.Env)
+	}
+	if cmd.Cwd != "/tmp" {
+		t.Errorf("wanted cwd /tmp; got %s", cmd.Cwd)
+	}
+	if !cmd.ClearEnv {
+		t.Errorf("wanted clear_env to be true")
+	}
+}
+
+func TestFnShell_invalidEnv(t *testing.T) {
+	thread := &starlark.Thread{}
+	builtin := &starlark.Builtin{}
+
+	invalidName := starlark.NewDict(1)
+	_ = invalidName.SetKey(starlark.String("A=B"), starlark.String("c"))
+
+	invalidValue := starlark.NewDict(1)
+	_ = invalidValue.SetKey(starlark.String("A"), starlark.MakeInt(1))
+
+	values := []starlark.Value{
+		starlark.String("A=b"),
+		invalidName,
+		invalidValue,
+	}
+
+	for _, value := range values {
+		args := []starlark.Value{starlark.String("foo")}
+		kwargs := []starlark.Tuple{{starlark.String("env"), value}}
+
+		_, err := FnShell(thread, builtin, args, kwargs)
+		if err == nil {
+			t.Errorf("wanted error for env %s", value)
+		}
+	}
+}
+
+func TestShellCmd_WithEnv(t *testing.T) {
+	cmd := ShellCmd{Command: "foo", Env: map[string]string{"A": "cmd", "B": "cmd"}}
+
+	merged := cmd.WithEnv(map[string]string{"B": "dep", "C": "dep"})
+
+	want := map[string]string{"A": "cmd", "B": "cmd", "C": "dep"}
+	if !reflect.DeepEqual(merged.Env, want) {
+		t.Errorf("wanted env %+v; got %+v", want, merged.Env)
+	}
+
+	// the original command is unchanged
+	if len(cmd.Env) != 2 {
+		t.Errorf("wanted original env unchanged; got %+v", cmd.Env)
+	}
+}
+
+func TestFnShell_sudo(t *testing.T) {
+	thread := &starlark.Thread{}
+	builtin := &starlark.Builtin{}
+
+	args := []starlark.Value{starlark.String("foo")}
+	kwargs := []starlark.Tuple{{starlark.String("sudo"), starlark.True}}
+
+	value, err := FnShell(thread, builtin, args, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnShell with args %+v, kwargs %+v", args, kwargs)
+	}
+
+	if !value.(ShellCmd).Sudo {
+		t.Error("wanted sudo to be true; got false")
+	}
+
+	kwargs = []starlark.Tuple{{starlark.String("sudo"), starlark.String("yes")}}
+	if _, err := FnShell(thread, builtin, args, kwargs); err == nil {
+		t.Error("wanted error for non-boolean sudo")
+	}
+}
+
+func TestFnShell_argv(t *testing.T) {
+	thread := &starlark.Thread{}
+
+	args := []starlark.Value{starlark.String("git"), starlark.String("clone"), starlark.String("my repo")}
+	for _, fn := range []func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error){FnShell, FnExec} {
+		value, err := fn(thread, &starlark.Builtin{}, args, nil)
+		if err != nil {
+			t.Fatalf("error running with args %+v: %s", args, err)
+		}
+
+		cmd := value.(ShellCmd)
+		if !reflect.DeepEqual(cmd.Argv, []string{"git", "clone", "my repo"}) {
+			t.Errorf("wanted argv [git clone my repo]; got %q", cmd.Argv)
+		}
+		if cmd.Command != "git clone 'my repo'" {
+			t.Errorf("wanted command \"git clone 'my repo'\"; got %q", cmd.Command)
+		}
+	}
+
+	// a single argument to exec is also an argument vector
+	value, err := FnExec(thread, &starlark.Builtin{}, []starlark.Value{starlark.String("true")}, nil)
+	if err != nil {
+		t.Fatalf("error running FnExec: %s", err)
+	}
+	if cmd := value.(ShellCmd); !reflect.DeepEqual(cmd.Argv, []string{"true"}) {
+		t.Errorf("wanted argv [true]; got %q", cmd.Argv)
+	}
+}
+
+func TestFnShell_invalidArgs(t *testing.T) {
+	thread := &starlark.Thread{}
+	builtin := &starlark.Builtin{}
+
+	type testCase struct {
+		args   []starlark.Value
+		kwargs []starlark.Tuple
+	}
+	testCases := []testCase{
+		{nil, nil},
+		{[]starlark.Value{starlark.String("")}, nil},
+		{[]starlark.Value{starlark.MakeInt(42)}, nil},
+		{[]starlark.Value{starlark.String("git"), starlark.MakeInt(42)}, nil},
+		{[]starlark.Value{starlark.String("foo")}, []starlark.Tuple{{starlark.String("sheel"), starlark.String("sh")}}},
+		{[]starlark.Value{starlark.String("git"), starlark.String("status")}, []starlark.Tuple{{starlark.String("shell"), starlark.String("sh")}}},
+		{[]starlark.Value{starlark.String("git"), starlark.String("status")}, []starlark.Tuple{{starlark.String("login"), starlark.True}}},
+	}
+
+	for _, testCase := range testCases {
+		value, err := FnShell(thread, builtin, testCase.args, testCase.kwargs)
+		if err == nil {
+			t.Errorf("wanted error for args %+v, kwargs %+v; got %s", testCase.args, testCase.kwargs, value)
+		}
+	}
+}
+
+func TestFnShell_fromStarlark(t *testing.T) {
+	thread := &starlark.Thread{}
+	globals := starlark.StringDict{"shell": shellBuiltin, "exec": execBuiltin}
+
+	for _, program := range []string{"shell()", "exec()", "shell(42)"} {
+		_, err := starlark.ExecFile(thread, "test.dep", "x = "+program, globals)
+		if err == nil {
+			t.Errorf("wanted error for %s", program)
+		}
+	}
+}
+
+func TestQuoteArgs(t *testing.T) {
+	testCases := map[string][]string{
+		"echo foo":             {"echo", "foo"},
+		"echo 'foo bar'":       {"echo", "foo bar"},
+		"echo ''":              {"echo", ""},
+		`echo 'it'"'"'s'`:      {"echo", "it's"},
+		"echo '$HOME'":         {"echo", "$HOME"},
+		"ls -la /tmp/a=b:c@d,": {"ls", "-la", "/tmp/a=b:c@d,"},
+	}
+
+	for want, args := range testCases {
+		if got := quoteArgs(args); got != want {
+			t.Errorf("wanted %s; got %s", want, got)
+		}
+	}
+}
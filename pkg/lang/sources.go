@@ -0,0 +1,48 @@
+package lang
+
+import (
+	"fmt"
+	"io/ioutil"
+	oslib "os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Sources returns the paths of the files read by the commands of the given
+// deps when they are run, i.e. the sources of their templates, sorted and
+// without duplicates. A path may start with "~", for the home directory of
+// the user.
+func Sources(deps ...*Dep) []string {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, dep := range deps {
+		for _, cmds := range [][]Command{dep.MetCommands, dep.MeetCommands} {
+			for _, cmd := range cmds {
+				tmpl, ok := cmd.(*Template)
+				if !ok || seen[tmpl.Src] {
+					continue
+				}
+				seen[tmpl.Src] = true
+				sources = append(sources, tmpl.Src)
+			}
+		}
+	}
+
+	sort.Strings(sources)
+	return sources
+}
+
+// readSource returns the contents of the source with the given path, as
+// returned by Sources.
+func readSource(path string) ([]byte, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := oslib.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("could not expand %s: %w", path, err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+
+	return ioutil.ReadFile(path)
+}
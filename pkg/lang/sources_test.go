@@ -0,0 +1,30 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSources(t *testing.T) {
+	foo := &Dep{
+		Name: "foo",
+		MetCommands: []Command{
+			&Template{Src: "/b.tmpl", Dst: "/b"},
+			&ShellCmd{Command: "true"},
+		},
+		MeetCommands: []Command{
+			&Template{Src: "/a.tmpl", Dst: "/a"},
+		},
+	}
+	bar := &Dep{
+		Name: "bar",
+		MetCommands: []Command{
+			&Template{Src: "/b.tmpl", Dst: "/c"},
+		},
+	}
+
+	want := []string{"/a.tmpl", "/b.tmpl"}
+	if got := Sources(foo, bar); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v; got %v", want, got)
+	}
+}
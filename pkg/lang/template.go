@@ -0,0 +1,149 @@
+package lang
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// Template represents the `template()` builtin function and models a file
+// rendered from a Go text/template.
+//
+// The structure of `template` is as follows:
+//
+//   template(
+//     'dotfiles/gitconfig.tmpl', // the path to the template
+//     '~/.gitconfig',            // the path of the rendered file
+//     vars={                     // the variables available to the template
+//       'email': 'me@example.com',
+//     },
+//   )
+//
+// Within the template, variables are accessed by name, e.g. `{{ .email }}`.
+// Relative paths are resolved relative to the directory of the module in
+// which the template is declared.
+type Template struct {
+
+	// Src is the path to the template.
+	Src string
+
+	// Dst is the path of the rendered file.
+	Dst string
+
+	// Vars are the variables available to the template, converted to their
+	// equivalent Go values.
+	Vars map[string]interface{}
+}
+
+// String returns the string representation of the Template.
+func (t Template) String() string {
+	return fmt.Sprintf("<dep.Template %q -> %q>", t.Src, t.Dst)
+}
+
+// Type returns a short description about Template's type.
+func (t Template) Type() string { return "dep.Template" }
+
+// Freeze does nothing for a Template.
+func (t Template) Freeze() {}
+
+// Truth always returns true for a Template.
+func (t Template) Truth() starlark.Bool { return starlark.True }
+
+// Hash is currently not implemented by Template.
+func (t Template) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", t.Type())
+}
+
+func (t Template) command()  {}
+func (t Template) resource() {}
+
+// FnTemplate implements the signature for a builtin function and implements
+// the functionality of the `template` function.
+func FnTemplate(t *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var src, dst string
+	var vars *starlark.Dict
+	err := starlark.UnpackArgs(fn.Name(), args, kwargs, "src", &src, "dst", &dst, "vars?", &vars)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedSrc, err := resolvePath(t, src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: src: %s", fn.Name(), err)
+	}
+
+	resolvedDst, err := resolvePath(t, dst)
+	if err != nil {
+		return nil, fmt.Errorf("%s: dst: %s", fn.Name(), err)
+	}
+
+	tmpl := &Template{Src: resolvedSrc, Dst: resolvedDst, Vars: map[string]interface{}{}}
+	if vars != nil {
+		converted, err := asGoMap(vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument to vars: %s", fn.Name(), err)
+		}
+		tmpl.Vars = converted
+	}
+
+	return tmpl, nil
+}
+
+// asGoMap converts the given Starlark dict into a map of Go values. The keys
+// of the dict must be strings.
+func asGoMap(dict *starlark.Dict) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, dict.Len())
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("key %s is not a string", item[0])
+		}
+
+		value, err := asGoValue(item[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// asGoValue converts the given Starlark value into its equivalent Go value.
+// Only None, bools, ints, strings, lists, tuples and dicts are supported.
+func asGoValue(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return nil, fmt.Errorf("int %s is too large", v)
+		}
+		return i, nil
+	case starlark.String:
+		return string(v), nil
+	case *starlark.List:
+		return asGoList(v)
+	case starlark.Tuple:
+		return asGoList(v)
+	case *starlark.Dict:
+		return asGoMap(v)
+	default:
+		return nil, fmt.Errorf("unsupported value of type %s", value.Type())
+	}
+}
+
+// asGoList converts the given Starlark sequence into a slice of Go values.
+func asGoList(seq starlark.Indexable) ([]interface{}, error) {
+	list := make([]interface{}, seq.Len())
+	for i := range list {
+		value, err := asGoValue(seq.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		list[i] = value
+	}
+	return list, nil
+}
@@ -0,0 +1,57 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestFnTemplate(t *testing.T) {
+	vars := starlark.NewDict(4)
+	_ = vars.SetKey(starlark.String("name"), starlark.String("foo"))
+	_ = vars.SetKey(starlark.String("count"), starlark.MakeInt(3))
+	_ = vars.SetKey(starlark.String("enabled"), starlark.True)
+	_ = vars.SetKey(starlark.String("editors"), starlark.NewList([]starlark.Value{starlark.String("vim")}))
+
+	args := starlark.Tuple{starlark.String("/src.tmpl"), starlark.String("/dst")}
+	kwargs := []starlark.Tuple{{starlark.String("vars"), vars}}
+
+	value, err := FnTemplate(&starlark.Thread{}, starlark.NewBuiltin("template", FnTemplate), args, kwargs)
+	if err != nil {
+		t.Fatalf("error running FnTemplate: %s", err)
+	}
+
+	tmpl := value.(*Template)
+	if tmpl.Src != "/src.tmpl" || tmpl.Dst != "/dst" {
+		t.Errorf("wanted template /src.tmpl -> /dst; got %s", tmpl)
+	}
+
+	want := map[string]interface{}{
+		"name":    "foo",
+		"count":   int64(3),
+		"enabled": true,
+		"editors": []interface{}{"vim"},
+	}
+	if !reflect.DeepEqual(tmpl.Vars, want) {
+		t.Errorf("wanted vars %+v; got %+v", want, tmpl.Vars)
+	}
+}
+
+func TestFnTemplate_InvalidVars(t *testing.T) {
+	builtin := starlark.NewBuiltin("template", FnTemplate)
+	args := starlark.Tuple{starlark.String("/src.tmpl"), starlark.String("/dst")}
+
+	nonStringKey := starlark.NewDict(1)
+	_ = nonStringKey.SetKey(starlark.MakeInt(1), starlark.String("foo"))
+
+	unsupported := starlark.NewDict(1)
+	_ = unsupported.SetKey(starlark.String("fn"), builtin)
+
+	for _, vars := range []*starlark.Dict{nonStringKey, unsupported} {
+		kwargs := []starlark.Tuple{{starlark.String("vars"), vars}}
+		if _, err := FnTemplate(&starlark.Thread{}, builtin, args, kwargs); err == nil {
+			t.Errorf("wanted error for vars %s", vars)
+		}
+	}
+}
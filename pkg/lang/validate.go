@@ -0,0 +1,147 @@
+package lang
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.starlark.net/syntax"
+)
+
+// DuplicateError is returned when more than one enabled dep is defined with
+// the same name.
+type DuplicateError struct {
+
+	// Name is the name shared by the deps.
+	Name string
+
+	// Positions are the positions at which each of the deps was defined.
+	Positions []syntax.Position
+}
+
+// Error returns the name of the duplicated dep, followed by the position of
+// each definition.
+func (e *DuplicateError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dep %q is defined more than once", e.Name)
+	for _, pos := range e.Positions {
+		fmt.Fprintf(&b, "\n  %s", pos)
+	}
+	return b.String()
+}
+
+// ValidateDeps checks the given deps, along with any deps they require, for
+// problems that are only apparent when considering the deps together. An
+// error is returned if two distinct enabled deps share a name. Deps that are
+// defined identically, e.g. by calling pkg() with the same arguments in two
+// modules, are not considered distinct.
+func ValidateDeps(deps []*Dep) error {
+	byName := make(map[string][]*Dep)
+	visited := make(map[*Dep]bool)
+
+	var visit func(dep *Dep)
+	visit = func(dep *Dep) {
+		if visited[dep] {
+			return
+		}
+		visited[dep] = true
+
+		if dep.Enable {
+			byName[dep.Name] = append(byName[dep.Name], dep)
+		}
+		for _, req := range dep.Requirements {
+			visit(req)
+		}
+	}
+	for _, dep := range deps {
+		visit(dep)
+	}
+
+	var dupes []*DuplicateError
+	for name, named := range byName {
+		var positions []syntax.Position
+		for i, dep := range named {
+			if i > 0 && sameDefinition(named[0], dep) {
+				continue
+			}
+			positions = append(positions, dep.Pos)
+		}
+		if len(positions) < 2 {
+			continue
+		}
+
+		sortPositions(positions)
+		dupes = append(dupes, &DuplicateError{Name: name, Positions: positions})
+	}
+	if len(dupes) == 0 {
+		return nil
+	}
+
+	// report the duplicate defined first, such that the error is stable
+	sort.Slice(dupes, func(i, j int) bool {
+		return positionLess(dupes[i].Positions[0], dupes[j].Positions[0])
+	})
+	return dupes[0]
+}
+
+// checkName returns an error if the given name is not a valid name for a dep.
+// A name must start with a letter or digit, followed by any number of letters,
+// digits, or any of "-_.+".
+func checkName(name string) error {
+	return checkIdentifier("name", name)
+}
+
+// checkIdentifier returns an error if the given value is not a valid
+// identifier, such as a name or a tag, described by the given kind. The rules
+// are those of checkName.
+func checkIdentifier(kind, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is empty", kind)
+	}
+
+	for i, r := range value {
+		alphanumeric := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+		if alphanumeric || i > 0 && strings.ContainsRune("-_.+", r) {
+			continue
+		}
+		return fmt.Errorf("%s %q contains invalid character %q; %ss may only contain letters, digits and \"-_.+\", and must start with a letter or digit", kind, value, r, kind)
+	}
+
+	return nil
+}
+
+// sameDefinition returns true if the given deps are defined identically,
+// ignoring the position of their definitions.
+func sameDefinition(a, b *Dep) bool {
+	x, y := *a, *b
+	x.Pos, y.Pos = syntax.Position{}, syntax.Position{}
+	return reflect.DeepEqual(x, y)
+}
+
+// positionError returns the given error prefixed with the given position, if
+// the position is valid.
+func positionError(pos syntax.Position, err error) error {
+	if !pos.IsValid() {
+		return err
+	}
+	return fmt.Errorf("%s: %w", pos, err)
+}
+
+// sortPositions sorts the given positions by file, line and column.
+func sortPositions(positions []syntax.Position) {
+	sort.Slice(positions, func(i, j int) bool {
+		return positionLess(positions[i], positions[j])
+	})
+}
+
+// positionLess returns true if position a sorts before position b.
+func positionLess(a, b syntax.Position) bool {
+	if a.Filename() != b.Filename() {
+		return a.Filename() < b.Filename()
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Col < b.Col
+}
@@ -0,0 +1,114 @@
+package lang
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+const duplicate = "./testcases/duplicate"
+
+func TestParser_Duplicate(t *testing.T) {
+	parser := NewParser(duplicate)
+
+	err := parser.Run()
+	if err == nil {
+		t.Fatal("wanted an error; got none")
+	}
+
+	var dupErr *DuplicateError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("wanted a DuplicateError; got %+v", err)
+	}
+
+	if dupErr.Name != "foo" {
+		t.Errorf("wanted duplicate 'foo'; got %s", dupErr.Name)
+	}
+
+	// each definition is reported, in order of the position of the definition
+	want := []string{"foo.dep:3:10", "main.dep:10:16"}
+	if len(dupErr.Positions) != len(want) {
+		t.Fatalf("wanted %d positions; got %+v", len(want), dupErr.Positions)
+	}
+	for i, pos := range dupErr.Positions {
+		got := filepath.Base(pos.Filename()) + ":" + strings.SplitN(pos.String(), ":", 2)[1]
+		if got != want[i] {
+			t.Errorf("wanted position %s; got %s", want[i], pos)
+		}
+	}
+
+	if !strings.Contains(err.Error(), `dep "foo" is defined more than once`) {
+		t.Errorf("wanted error to name the duplicate; got %s", err)
+	}
+}
+
+func TestValidateDeps_Identical(t *testing.T) {
+	args := starlark.Tuple{starlark.String("git")}
+	first, err := FnPkg(&starlark.Thread{}, pkgBuiltin, args, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := FnPkg(&starlark.Thread{}, pkgBuiltin, args, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := []*Dep{first.(*Dep), second.(*Dep)}
+	if err := ValidateDeps(deps); err != nil {
+		t.Errorf("did not expect error for identical deps; got %s", err)
+	}
+
+	// a dep that differs is a duplicate
+	deps[1].Description = "git, but different"
+	if err := ValidateDeps(deps); err == nil {
+		t.Error("wanted error for distinct deps sharing a name")
+	}
+
+	// unless it is disabled
+	deps[1].Enable = false
+	if err := ValidateDeps(deps); err != nil {
+		t.Errorf("did not expect error with a disabled dep; got %s", err)
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"all", "foo-linux", "g++", "python3.9", "foo_bar", "1password"} {
+		if err := checkName(name); err != nil {
+			t.Errorf("wanted name %q to be valid; got %s", name, err)
+		}
+	}
+
+	for _, name := range []string{"", "-foo", "foo bar", "foo/bar", "foo:bar", "über"} {
+		if err := checkName(name); err == nil {
+			t.Errorf("wanted name %q to be invalid", name)
+		}
+	}
+}
+
+func TestFnDep_invalid(t *testing.T) {
+	globals := starlark.StringDict{"dep": depBuiltin}
+
+	testCases := map[string]string{
+		`dep()`:                                "dep: missing name",
+		`dep('foo')`:                           "dep: unexpected positional arguments",
+		`dep(name = 'foo', bar = 1)`:           `dep: unexpected keyword argument "bar"`,
+		`dep(name = 'foo bar')`:                `dep: name "foo bar" contains invalid character ' '`,
+		`dep(name = 'foo', requirements = [])`: `(did you mean "requires"?)`,
+	}
+
+	for program, want := range testCases {
+		_, err := starlark.ExecFile(&starlark.Thread{}, "test.dep", "\nx = "+program, globals)
+		if err == nil {
+			t.Errorf("wanted error for %s", program)
+			continue
+		}
+
+		// errors carry the position of the dep() call
+		if !strings.HasPrefix(err.Error(), "test.dep:2:8: ") || !strings.Contains(err.Error(), want) {
+			t.Errorf("wanted error for %s at test.dep:2:8 containing %q; got %s", program, want, err)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package state records the outcome of each apply in a local store, such that
+// past runs can be queried.
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nicktrav/matryoshka/pkg/graph"
+)
+
+const (
+	// appName is the name of the directory of the store within the state
+	// directory
+	appName = "matryoshka"
+
+	// runsFile is the file to which the runs are appended, one per line
+	runsFile = "runs.jsonl"
+)
+
+// Run records a single apply.
+type Run struct {
+
+	// ID identifies the run.
+	ID string `json:"id"`
+
+	// Start is the time at which the run started.
+	Start time.Time `json:"start"`
+
+	// End is the time at which the run ended.
+	End time.Time `json:"end"`
+
+	// Dir is the absolute path of the directory of deps that was applied.
+	Dir string `json:"dir"`
+
+	// Roots are the names of the deps applied, along with their requirements.
+	Roots []string `json:"roots"`
+
+	// DryRun is true if the meet actions were not run.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Deps are the outcome of each dep, in the order in which they completed.
+	Deps []*DepRun `json:"deps"`
+}
+
+// DepRun records the outcome of a single dep in a Run.
+type DepRun struct {
+
+	// Name is the name of the dep.
+	Name string `json:"name"`
+
+	// Definition is the digest of the definition of the dep when applied.
+	Definition string `json:"definition"`
+
+	// Closure is the digest of the definition of the dep, along with those
+	// of the deps it requires, when applied.
+	Closure string `json:"closure,omitempty"`
+
+	// State is the final state of the dep.
+	State graph.State `json:"state"`
+
+	// Error is the reason the dep is not satisfied, if any.
+	Error string `json:"error,omitempty"`
+
+	// Cached is true if the dep was satisfied without being evaluated, as it
+	// was recently verified, or was verified when the plan applied was made.
+	Cached bool `json:"cached,omitempty"`
+
+	// Attempts is the number of times the meet actions were attempted, or
+	// zero if the dep was already satisfied.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Start is the time at which the dep started.
+	Start time.Time `json:"start"`
+
+	// End is the time at which the dep completed.
+	End time.Time `json:"end"`
+}
+
+// NewRun returns a Run of the deps in the given dir from the given roots, as
+// recorded in the given Report.
+func NewRun(dir string, roots []string, dryRun bool, report *graph.Report) *Run {
+	run := &Run{
+		ID:     report.Start.UTC().Format("20060102T150405.000Z"),
+		Start:  report.Start,
+		End:    report.End,
+		Dir:    dir,
+		Roots:  roots,
+		DryRun: dryRun,
+		Deps:   []*DepRun{},
+	}
+
+	for _, dep := range report.Deps {
+		run.Deps = append(run.Deps, &DepRun{
+			Name:       dep.Name,
+			Definition: dep.Definition,
+			Closure:    dep.Closure,
+			State:      dep.State,
+			Error:      dep.Error,
+			Cached:     dep.Cached,
+			Attempts:   dep.Attempts,
+			Start:      dep.Start,
+			End:        dep.Start.Add(time.Duration(dep.DurationSeconds * float64(time.Second))),
+		})
+	}
+
+	return run
+}
+
+// Fresh returns true if the DepRun verified that the dep was satisfied, with
+// the given Closure, within the given TTL of the given time.
+func (d *DepRun) Fresh(closure string, ttl time.Duration, now time.Time) bool {
+	return d.State == graph.Satisfied && !d.Cached && d.Closure != "" &&
+		d.Closure == closure && now.Sub(d.End) <= ttl
+}
+
+// Converged returns true if the DepRun evaluated the dep, and left it
+// satisfied, whether or not its meet actions were run.
+func (d *DepRun) Converged() bool {
+	return d.State == graph.Satisfied && !d.Cached
+}
+
+// Enforced returns true if the DepRun satisfied the dep by running its meet
+// actions, rather than finding it already satisfied.
+func (d *DepRun) Enforced() bool {
+	return d.Converged() && d.Attempts > 0
+}
+
+// Dep returns the outcome of the dep with the given name in the Run, or nil
+// if the dep was not part of the Run.
+func (r *Run) Dep(name string) *DepRun {
+	for _, dep := range r.Deps {
+		if dep.Name == name {
+			return dep
+		}
+	}
+	return nil
+}
+
+// Store is a local store of past runs.
+type Store struct {
+
+	// dir is the directory containing the store
+	dir string
+}
+
+// NewStore returns a Store in the given directory. The directory is created
+// once the first run is recorded.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the default directory of the Store, within the XDG state
+// directory: $XDG_STATE_HOME/matryoshka, or ~/.local/state/matryoshka if
+// XDG_STATE_HOME is unset.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("state: %s", err)
+	}
+	return filepath.Join(home, ".local", "state", appName), nil
+}
+
+// Record appends the given Run to the Store.
+func (s *Store) Record(run *Run) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("state: %s", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("state: %s", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, runsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("state: %s", err)
+	}
+
+	// the run is written with a single write, such that runs recorded
+	// concurrently are not interleaved
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("state: %s", err)
+	}
+
+	return f.Close()
+}
+
+// Runs returns the runs in the Store, in the order in which they were
+// recorded. No runs are returned if none have been recorded.
+func (s *Store) Runs() ([]*Run, error) {
+	f, err := os.Open(filepath.Join(s.dir, runsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("state: %s", err)
+	}
+	defer f.Close()
+
+	var runs []*Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		run := &Run{}
+		if err := json.Unmarshal(scanner.Bytes(), run); err != nil {
+			return nil, fmt.Errorf("state: %s:%d: %s", runsFile, n, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("state: %s", err)
+	}
+
+	return runs, nil
+}
+
+// History returns the runs in the Store that include the dep with the given
+// name, in the order in which they were recorded.
+func (s *Store) History(name string) ([]*Run, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []*Run
+	for _, run := range runs {
+		if run.Dep(name) != nil {
+			history = append(history, run)
+		}
+	}
+	return history, nil
+}
+
+// Verifications returns the last verification of each dep that is still
+// valid, keyed by the name of the dep. A dep is verified when its met actions
+// succeed, with or without its meet actions having been run, and a
+// verification is invalidated by any later evaluation in which the dep is not
+// satisfied. Deps that were cached, blocked or skipped were not evaluated, so
+// neither verify nor invalidate a dep.
+func (s *Store) Verifications() (map[string]*DepRun, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	verified := make(map[string]*DepRun)
+	for _, run := range runs {
+		for _, dep := range run.Deps {
+			switch {
+			case dep.Cached, dep.State == graph.Blocked, dep.State == graph.Skipped, dep.State == graph.Unknown:
+			case dep.State == graph.Satisfied:
+				verified[dep.Name] = dep
+			default:
+				delete(verified, dep.Name)
+			}
+		}
+	}
+	return verified, nil
+}
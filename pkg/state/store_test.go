@@ -0,0 +1,241 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nicktrav/matryoshka/pkg/graph"
+)
+
+// tempDir returns a new temporary directory, and a function to remove it.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestDir(t *testing.T) {
+	old := os.Getenv("XDG_STATE_HOME")
+	defer os.Setenv("XDG_STATE_HOME", old)
+
+	os.Setenv("XDG_STATE_HOME", "/var/state")
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if dir != "/var/state/matryoshka" {
+		t.Errorf("wanted /var/state/matryoshka; got %s", dir)
+	}
+
+	// a relative path is ignored, as required by the XDG specification
+	os.Setenv("XDG_STATE_HOME", "state")
+	dir, err = Dir()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	home, _ := os.UserHomeDir()
+	if want := filepath.Join(home, ".local", "state", "matryoshka"); dir != want {
+		t.Errorf("wanted %s; got %s", want, dir)
+	}
+}
+
+func TestNewRun(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := &graph.Report{
+		Start: start,
+		End:   start.Add(3 * time.Second),
+		Deps: []*graph.DepReport{
+			{Name: "foo", Definition: "sha256:foo", State: graph.Satisfied, Attempts: 1, Start: start, DurationSeconds: 1.5},
+			{Name: "all", Definition: "sha256:all", State: graph.Failed, Error: "oh noes", Start: start, DurationSeconds: 3},
+		},
+	}
+
+	run := NewRun("/deps", []string{"all"}, false, report)
+
+	if run.ID != "20200101T000000.000Z" {
+		t.Errorf("wanted ID from start time; got %s", run.ID)
+	}
+
+	want := &DepRun{
+		Name:       "foo",
+		Definition: "sha256:foo",
+		State:      graph.Satisfied,
+		Attempts:   1,
+		Start:      start,
+		End:        start.Add(1500 * time.Millisecond),
+	}
+	if got := run.Dep("foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %+v; got %+v", want, got)
+	}
+
+	if run.Dep("bar") != nil {
+		t.Errorf("wanted no dep bar")
+	}
+}
+
+func TestStore_RecordRuns(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// the store is created once the first run is recorded
+	store := NewStore(filepath.Join(dir, "matryoshka"))
+	runs, err := store.Runs()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("wanted no runs; got %d", len(runs))
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := &Run{ID: "1", Start: start, Dir: "/deps", Roots: []string{"all"}, Deps: []*DepRun{
+		{Name: "foo", Definition: "sha256:foo", State: graph.Failed, Error: "oh noes"},
+	}}
+	second := &Run{ID: "2", Start: start.Add(time.Hour), Dir: "/deps", Roots: []string{"bar"}, DryRun: true, Deps: []*DepRun{
+		{Name: "bar", Definition: "sha256:bar", State: graph.WouldMeet},
+	}}
+	for _, run := range []*Run{first, second} {
+		if err := store.Record(run); err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+	}
+
+	runs, err = store.Runs()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if !reflect.DeepEqual(runs, []*Run{first, second}) {
+		t.Errorf("wanted %+v; got %+v", []*Run{first, second}, runs)
+	}
+
+	history, err := store.History("foo")
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(history) != 1 || history[0].ID != "1" {
+		t.Errorf("wanted the first run; got %+v", history)
+	}
+}
+
+func TestStore_Runs_Malformed(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, runsFile), []byte("{}\nnot json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewStore(dir).Runs()
+	if err == nil {
+		t.Fatalf("wanted an error")
+	}
+	if want := "state: runs.jsonl:2: invalid character 'o' in literal null (expecting 'u')"; err.Error() != want {
+		t.Errorf("wanted error %q; got %q", want, err)
+	}
+}
+
+func TestDepRun_Fresh(t *testing.T) {
+	end := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	dep := &DepRun{Name: "foo", Closure: "sha256:foo", State: graph.Satisfied, End: end}
+
+	if !dep.Fresh("sha256:foo", time.Hour, end.Add(time.Hour)) {
+		t.Errorf("wanted fresh within the TTL")
+	}
+	if dep.Fresh("sha256:foo", time.Hour, end.Add(time.Hour+time.Second)) {
+		t.Errorf("wanted stale after the TTL")
+	}
+	if dep.Fresh("sha256:changed", time.Hour, end) {
+		t.Errorf("wanted stale with a changed closure")
+	}
+
+	dep.State = graph.Failed
+	if dep.Fresh("sha256:foo", time.Hour, end) {
+		t.Errorf("wanted stale once failed")
+	}
+}
+
+func TestDepRun_Converged(t *testing.T) {
+	testCases := []struct {
+		name      string
+		dep       *DepRun
+		converged bool
+		enforced  bool
+	}{
+		{
+			name:      "met by the meet actions",
+			dep:       &DepRun{State: graph.Satisfied, Attempts: 1},
+			converged: true,
+			enforced:  true,
+		},
+		{
+			name:      "already met",
+			dep:       &DepRun{State: graph.Satisfied},
+			converged: true,
+		},
+		{
+			name: "cached",
+			dep:  &DepRun{State: graph.Satisfied, Cached: true},
+		},
+		{
+			name: "failed",
+			dep:  &DepRun{State: graph.Failed, Attempts: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.dep.Converged(); got != tc.converged {
+			t.Errorf("%s: wanted converged %t; got %t", tc.name, tc.converged, got)
+		}
+		if got := tc.dep.Enforced(); got != tc.enforced {
+			t.Errorf("%s: wanted enforced %t; got %t", tc.name, tc.enforced, got)
+		}
+	}
+}
+
+func TestStore_Verifications(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	store := NewStore(dir)
+
+	runs := []*Run{
+		{ID: "1", Deps: []*DepRun{
+			{Name: "foo", State: graph.Satisfied},
+			{Name: "bar", State: graph.Satisfied},
+			{Name: "baz", State: graph.Satisfied},
+			{Name: "qux", State: graph.Satisfied},
+		}},
+		{ID: "2", Deps: []*DepRun{
+			// cached, blocked and skipped deps were not evaluated
+			{Name: "foo", State: graph.Satisfied, Cached: true},
+			{Name: "bar", State: graph.Blocked},
+			{Name: "baz", State: graph.Skipped},
+			// a failure invalidates the last verification
+			{Name: "qux", State: graph.Failed},
+		}},
+	}
+	for _, run := range runs {
+		if err := store.Record(run); err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+	}
+
+	verifications, err := store.Verifications()
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		if v, ok := verifications[name]; !ok || v.Cached {
+			t.Errorf("wanted %s verified by the first run; got %+v", name, v)
+		}
+	}
+	if v, ok := verifications["qux"]; ok {
+		t.Errorf("wanted qux not verified; got %+v", v)
+	}
+}